@@ -0,0 +1,55 @@
+// Package aad centralizes the Azure AD v2 endpoint URLs and redirect
+// conventions shared by the AAD-based auth methods in internal/proxy/providers,
+// so a tenant/endpoint change only needs to happen in one place.
+package aad
+
+import (
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// Endpoint kinds accepted by Endpoint.
+const (
+	EndpointAuthorize  = "authorize"
+	EndpointToken      = "token"
+	EndpointDeviceCode = "devicecode"
+)
+
+// commonTenant is used when a method doesn't have a specific tenant ID
+// configured, e.g. a multi-tenant app doing device flow.
+const commonTenant = "common"
+
+// Endpoint builds the AAD v2 URL for kind (one of EndpointAuthorize,
+// EndpointToken or EndpointDeviceCode) under tenantID, defaulting to the
+// "common" multi-tenant endpoint when tenantID is empty.
+func Endpoint(kind, tenantID string) string {
+	if tenantID == "" {
+		tenantID = commonTenant
+	}
+	return fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/%s", tenantID, kind)
+}
+
+// AuthCodeEndpoint returns the oauth2.Endpoint for the authorization
+// code grant under tenantID.
+func AuthCodeEndpoint(tenantID string) oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  Endpoint(EndpointAuthorize, tenantID),
+		TokenURL: Endpoint(EndpointToken, tenantID),
+	}
+}
+
+// DeviceFlowEndpoint returns the oauth2.Endpoint for the device
+// authorization grant under tenantID.
+func DeviceFlowEndpoint(tenantID string) oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  Endpoint(EndpointDeviceCode, tenantID),
+		TokenURL: Endpoint(EndpointToken, tenantID),
+	}
+}
+
+// CallbackPath builds the local redirect URL the authorization code
+// flow's loopback listener receives the response on.
+func CallbackPath(addr string) string {
+	return fmt.Sprintf("http://%s/callback", addr)
+}
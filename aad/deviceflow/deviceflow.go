@@ -0,0 +1,170 @@
+// Package deviceflow implements the AAD device authorization grant
+// (RFC 8628): a user visits a verification URL and enters a short code
+// while this process polls the token endpoint, so headless hosts (CI
+// runners, SSH sessions) can authenticate without a local browser.
+package deviceflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"azure-blockchain-connector/aad"
+
+	"golang.org/x/oauth2"
+)
+
+// Config describes the device flow's AAD app registration.
+type Config struct {
+	Endpoint oauth2.Endpoint
+	ClientID string
+	Scopes   []string
+}
+
+// Code is the device/user code pair returned by the device authorization
+// endpoint.
+type Code struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+	Message         string `json:"message"`
+}
+
+// RequestCode starts a device flow, returning the code the user must
+// enter at VerificationURI.
+func (c *Config) RequestCode(ctx context.Context) (*Code, error) {
+	form := url.Values{
+		"client_id": {c.ClientID},
+		"scope":     {strings.Join(c.Scopes, " ")},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint.AuthURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var code Code
+	if err := json.NewDecoder(resp.Body).Decode(&code); err != nil {
+		return nil, fmt.Errorf("decoding device code response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || code.DeviceCode == "" {
+		return nil, fmt.Errorf("device code endpoint returned status %d", resp.StatusCode)
+	}
+	return &code, nil
+}
+
+// Poll polls the token endpoint until the user completes sign-in,
+// code expires, or ctx is done.
+func (c *Config) Poll(ctx context.Context, code *Code) (*oauth2.Token, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("device code expired before authorization completed")
+		}
+
+		tok, pending, err := c.poll(ctx, code.DeviceCode)
+		if err != nil {
+			return nil, err
+		}
+		if pending {
+			continue
+		}
+		return tok, nil
+	}
+}
+
+func (c *Config) poll(ctx context.Context, deviceCode string) (tok *oauth2.Token, pending bool, err error) {
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"client_id":   {c.ClientID},
+		"device_code": {deviceCode},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("polling for device flow token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Error        string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("decoding device flow token response: %w", err)
+	}
+
+	switch body.Error {
+	case "":
+	case "authorization_pending", "slow_down":
+		return nil, true, nil
+	default:
+		return nil, false, fmt.Errorf("device flow error: %s", body.Error)
+	}
+	if body.AccessToken == "" {
+		return nil, false, fmt.Errorf("device flow token response missing access_token")
+	}
+	return &oauth2.Token{
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, false, nil
+}
+
+// TokenSource returns an oauth2.TokenSource that auto-refreshes via the
+// standard refresh_token grant. If seed already holds a refresh token
+// (e.g. loaded from an on-disk cache), it's reused as-is; otherwise the
+// interactive device flow is run once to obtain one. The returned
+// source forces a real AAD round trip on every call (see
+// aad.ForceRefreshSource), so a caller's proactive refresh window isn't
+// silently defeated by oauth2.Config's own short-lived caching.
+func (c *Config) TokenSource(ctx context.Context, seed *oauth2.Token) (oauth2.TokenSource, error) {
+	cfg := &oauth2.Config{ClientID: c.ClientID, Endpoint: c.Endpoint, Scopes: c.Scopes}
+
+	refreshToken := ""
+	if seed != nil && seed.RefreshToken != "" {
+		refreshToken = seed.RefreshToken
+	} else {
+		code, err := c.RequestCode(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println(code.Message)
+
+		tok, err := c.Poll(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		refreshToken = tok.RefreshToken
+	}
+	return aad.NewForceRefreshSource(ctx, cfg, refreshToken), nil
+}
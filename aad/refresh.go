@@ -0,0 +1,57 @@
+package aad
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ForceRefreshSource wraps a refresh-token-based oauth2.Config flow so
+// every call to Token() performs a genuine round trip to AAD.
+// oauth2.Config.TokenSource caches internally and only treats a token as
+// due for renewal ~10 seconds before its real expiry, which would
+// silently defeat a proactive refresh window of several minutes:
+// callers expecting an early refresh would just get the same
+// soon-to-expire token back with no network call made. Feeding in a
+// token that already looks expired, but carries the live refresh token,
+// forces the wrapped source to exchange it every call; the proactive
+// caching itself is expected to live in the caller (e.g.
+// providers.TokenCache).
+type ForceRefreshSource struct {
+	ctx    context.Context
+	config *oauth2.Config
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+// NewForceRefreshSource returns a ForceRefreshSource seeded with
+// refreshToken.
+func NewForceRefreshSource(ctx context.Context, config *oauth2.Config, refreshToken string) *ForceRefreshSource {
+	return &ForceRefreshSource{ctx: ctx, config: config, refreshToken: refreshToken}
+}
+
+// Token implements oauth2.TokenSource.
+func (s *ForceRefreshSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	refreshToken := s.refreshToken
+	s.mu.Unlock()
+
+	expired := &oauth2.Token{RefreshToken: refreshToken, Expiry: time.Now().Add(-time.Hour)}
+	tok, err := s.config.TokenSource(s.ctx, expired).Token()
+	if err != nil {
+		return nil, err
+	}
+	// AAD doesn't always rotate the refresh token; keep the last one we
+	// were given if the response omits a new one.
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = refreshToken
+	}
+
+	s.mu.Lock()
+	s.refreshToken = tok.RefreshToken
+	s.mu.Unlock()
+	return tok, nil
+}
@@ -3,14 +3,22 @@ package main
 import (
 	"azure-blockchain-connector/aad"
 	"azure-blockchain-connector/aad/deviceflow"
-	"azure-blockchain-connector/proxy"
-	"azure-blockchain-connector/proxy/providers"
+	"azure-blockchain-connector/internal/proxy"
+	"azure-blockchain-connector/internal/proxy/logging"
+	"azure-blockchain-connector/internal/proxy/providers"
+	"azure-blockchain-connector/internal/proxy/telemetry"
+	"context"
 	"flag"
 	"fmt"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 const (
@@ -35,7 +43,7 @@ func checkStr(ss ...string) {
 func newProxyFromFlags() *proxy.Proxy {
 	var params = &proxy.Params{}
 
-	flag.StringVar(&params.Method, "method", proxy.MethodBasicAuth, "Authentication method. Basic auth (basic), authorization code (authcode), client credentials (client) and device flow(device)")
+	flag.StringVar(&params.Method, "method", proxy.MethodBasicAuth, "Authentication method. Basic auth (basic), authorization code (authcode), client credentials (client), device flow (device), managed identity (msi), workload identity (workload), client certificate (cert) and a DefaultAzureCredential-style chain of workload/managed identity (default)")
 	flag.StringVar(&params.Local, "local", defaultLocalAddr, "Local address to bind to")
 	flag.StringVar(&params.Remote, "remote", "", "Remote endpoint address")
 
@@ -59,6 +67,72 @@ func newProxyFromFlags() *proxy.Proxy {
 	flag.BoolVar(&useWebview, "webview", true, "OAuth: open a webview o to receive callbacks, applicable for Windows/macOS")
 	flag.StringVar(&authSvcAddr, "authcode-addr", defaultLocalAddr, "OAuth: local address to receive callbacks")
 
+	var clientSecretRef string
+	flag.StringVar(&clientSecretRef, "client-secret-ref", "", "(Optional) Resolve the client secret from a reference instead of -client-secret, e.g. keyvault://<vault>/<secret>[/<version>]")
+
+	// client certificate authentication (-method=cert)
+	var (
+		keySource     string
+		keyPath       string
+		keyPass       string
+		certPEMPath   string
+		keyVaultKeyID string
+		keyAlgorithm  string
+		pkcs11Module  string
+		pkcs11PIN     string
+		pkcs11Label   string
+	)
+	flag.StringVar(&keySource, "key-source", providers.KeySourceFile, "Client certificate: where the signing key lives. Alternatives: file, keyvault and pkcs11")
+	flag.StringVar(&keyPath, "key-path", "", "Client certificate (file key source): path to a PEM or PKCS#12 file containing the private key and certificate")
+	flag.StringVar(&keyPass, "key-pass", "", "Client certificate (file key source): PKCS#12 passphrase. Encrypted PEM keys are not supported")
+	flag.StringVar(&certPEMPath, "cert-pem", "", "Client certificate (keyvault key source): path to the public certificate PEM, for the x5t#S256 header")
+	flag.StringVar(&keyVaultKeyID, "key-vault-key-id", "", "Client certificate (keyvault key source): full key version URL, e.g. https://<vault>.vault.azure.net/keys/<name>/<version>")
+	flag.StringVar(&keyAlgorithm, "key-algorithm", "RS256", "Client certificate: JWS signing algorithm. Alternatives: RS256 and PS256")
+	flag.StringVar(&pkcs11Module, "pkcs11-module", "", "Client certificate (pkcs11 key source): path to the PKCS#11 provider shared library")
+	flag.StringVar(&pkcs11PIN, "pkcs11-pin", "", "Client certificate (pkcs11 key source): token PIN")
+	flag.StringVar(&pkcs11Label, "pkcs11-label", "", "Client certificate (pkcs11 key source): label of the key/certificate pair to use")
+
+	// Managed Identity / Workload Identity
+	var (
+		msiResource        string
+		msiObjectID        string
+		msiResourceID      string
+		federatedTokenFile string
+	)
+	flag.StringVar(&msiResource, "msi-resource", "https://management.azure.com/", "Managed/workload identity: resource URI to request a token for")
+	flag.StringVar(&msiObjectID, "msi-object-id", "", "Managed identity: object ID of the user-assigned identity (mutually exclusive with client-id/msi-resource-id)")
+	flag.StringVar(&msiResourceID, "msi-resource-id", "", "Managed identity: ARM resource ID of the user-assigned identity (mutually exclusive with client-id/msi-object-id)")
+	flag.StringVar(&federatedTokenFile, "federated-token-file", "", "Workload identity: path to the projected service account token. Defaults to $AZURE_FEDERATED_TOKEN_FILE")
+
+	// OAuth token caching
+	flag.StringVar(&params.TokenCachePath, "token-cache-path", "", "(Optional) File path to persist an encrypted OAuth token cache, so device-flow/auth-code users aren't re-prompted on every restart")
+	flag.DurationVar(&params.TokenRefreshWindow, "token-refresh-window", 5*time.Minute, "OAuth: how far ahead of expiry a cached token is proactively refreshed")
+
+	// retry policy
+	var retryStatusCodesStr string
+	flag.IntVar(&params.RetryMax, "retry-max", 0, "Max number of retries for idempotent/empty-body requests on transport errors or a retry-status-codes status. 0 disables retries")
+	flag.DurationVar(&params.RetryBaseDelay, "retry-base-delay", 200*time.Millisecond, "Base delay for full-jitter exponential backoff between retries")
+	flag.DurationVar(&params.RetryMaxDelay, "retry-max-delay", 10*time.Second, "Max delay for full-jitter exponential backoff between retries")
+	flag.StringVar(&retryStatusCodesStr, "retry-status-codes", "408,429,500,502,503,504", "Comma-separated response status codes that are retried")
+
+	// shared transport
+	var (
+		httpVersion           string
+		maxIdleConnsPerHost   int
+		idleConnTimeout       time.Duration
+		responseHeaderTimeout time.Duration
+		expectContinueTimeout time.Duration
+		http2ReadIdleTimeout  time.Duration
+		http2PingTimeout      time.Duration
+	)
+	flag.StringVar(&httpVersion, "http-version", proxy.HTTPVersionAuto, "HTTP protocol version to the remote endpoint. Alternatives: auto, 1.1 and 2")
+	flag.IntVar(&maxIdleConnsPerHost, "max-idle-conns-per-host", 64, "Max idle (keep-alive) connections to keep per host")
+	flag.DurationVar(&idleConnTimeout, "idle-conn-timeout", 90*time.Second, "How long an idle connection is kept in the pool before being closed")
+	flag.DurationVar(&responseHeaderTimeout, "response-header-timeout", 0, "(Optional) Time to wait for a response's headers after the request is fully written. 0 means no timeout")
+	flag.DurationVar(&expectContinueTimeout, "expect-continue-timeout", time.Second, "Time to wait for a 100-continue response when Expect: 100-continue is sent")
+	flag.DurationVar(&http2ReadIdleTimeout, "http2-read-idle-timeout", 30*time.Second, "HTTP/2: send a PING if no frame is read for this long on an idle connection")
+	flag.DurationVar(&http2PingTimeout, "http2-ping-timeout", 15*time.Second, "HTTP/2: close the connection if a PING ack doesn't arrive within this long")
+
 	var whenlogstr string
 	var whatlogstr string
 	var debugmode bool
@@ -66,15 +140,69 @@ func newProxyFromFlags() *proxy.Proxy {
 	flag.StringVar(&whatlogstr, "whatlog", proxy.LogWhatBasic, "Configuration about what information should be included in logs. Alternatives: basic and detailed")
 	flag.BoolVar(&debugmode, "debugmode", false, "Open debug mode. It will set whenlog to always and whatlog to detailed, and original settings for whenlog and whatlog are covered.")
 
+	// structured logging
+	var (
+		logFormat     string
+		logFile       string
+		authLogFile   string
+		logMaxSize    int
+		logMaxAge     int
+		logMaxBackups int
+		logCompress   bool
+	)
+	flag.StringVar(&logFormat, "log-format", logging.FormatJSON, "Log output format. Alternatives: json and text")
+	flag.StringVar(&logFile, "log-file", "", "(Optional) File path for the request log stream. Defaults to stdout")
+	flag.StringVar(&authLogFile, "auth-log-file", "", "(Optional) File path for the auth/provider log stream. Defaults to stderr")
+	flag.IntVar(&logMaxSize, "log-max-size", 100, "Request/auth log: max size in megabytes before rotation")
+	flag.IntVar(&logMaxAge, "log-max-age", 28, "Request/auth log: max age in days to retain rotated files")
+	flag.IntVar(&logMaxBackups, "log-max-backups", 3, "Request/auth log: max number of rotated files to retain")
+	flag.BoolVar(&logCompress, "log-compress", false, "Request/auth log: gzip-compress rotated files")
+
+	// observability
+	var (
+		serviceName    string
+		otelEndpoint   string
+		otelSampleRate float64
+		metricsAddr    string
+	)
+	flag.StringVar(&serviceName, "service-name", "azure-blockchain-connector", "Service name reported to the OTel collector")
+	flag.StringVar(&otelEndpoint, "otel-endpoint", "", "(Optional) OTLP/gRPC collector endpoint, e.g. localhost:4317. Empty disables trace export")
+	flag.Float64Var(&otelSampleRate, "otel-sample-ratio", 1.0, "Fraction of traces to sample, between 0.0 and 1.0")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "(Optional) Address to bind the /metrics endpoint to, e.g. 127.0.0.1:9090. Empty disables it")
+
 	flag.Parse()
 
 	switch params.Method {
-	case proxy.MethodBasicAuth, proxy.MethodOAuthAuthCode, proxy.MethodOAuthDeviceFlow, proxy.MethodOAuthClientCredentials:
+	case proxy.MethodBasicAuth, proxy.MethodOAuthAuthCode, proxy.MethodOAuthDeviceFlow, proxy.MethodOAuthClientCredentials,
+		proxy.MethodManagedIdentity, proxy.MethodWorkloadIdentity, proxy.MethodOAuthClientCertificate, proxy.MethodDefault:
 	default:
-		fmt.Println("Unexpected method value. Expected: basic, authcode, device")
+		fmt.Println("Unexpected method value. Expected: basic, authcode, client, device, msi, workload, cert or default")
 		os.Exit(-1)
 	}
 
+	switch keySource {
+	case providers.KeySourceFile, providers.KeySourceKeyVault, providers.KeySourcePKCS11:
+	default:
+		fmt.Println("Unexpected key-source value. Expected: file, keyvault or pkcs11")
+		os.Exit(-1)
+	}
+
+	switch keyAlgorithm {
+	case "RS256", "PS256":
+	default:
+		fmt.Println("Unexpected key-algorithm value. Expected: RS256 or PS256")
+		os.Exit(-1)
+	}
+
+	if clientSecretRef != "" {
+		resolved, err := providers.ResolveSecretRef(clientSecretRef)
+		if err != nil {
+			fmt.Println("Failed to resolve client-secret-ref:", err)
+			os.Exit(-1)
+		}
+		clientSecret = resolved
+	}
+
 	switch whenlogstr {
 	case proxy.LogWhenOnError, proxy.LogWhenOnNon200, proxy.LogWhenAlways:
 	default:
@@ -89,6 +217,38 @@ func newProxyFromFlags() *proxy.Proxy {
 		os.Exit(-1)
 	}
 
+	switch logFormat {
+	case logging.FormatJSON, logging.FormatText:
+	default:
+		fmt.Println("Unexpected log-format value. Expected: json or text")
+		os.Exit(-1)
+	}
+
+	switch httpVersion {
+	case proxy.HTTPVersionAuto, proxy.HTTPVersion11, proxy.HTTPVersion2:
+	default:
+		fmt.Println("Unexpected http-version value. Expected: auto, 1.1 or 2")
+		os.Exit(-1)
+	}
+
+	if otelSampleRate < 0 || otelSampleRate > 1 {
+		fmt.Println("Unexpected otel-sample-ratio value. Expected a number between 0.0 and 1.0")
+		os.Exit(-1)
+	}
+
+	for _, s := range strings.Split(retryStatusCodesStr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			fmt.Println("Unexpected retry-status-codes value:", s)
+			os.Exit(-1)
+		}
+		params.RetryStatusCodes = append(params.RetryStatusCodes, code)
+	}
+
 	if debugmode {
 		params.Whenlog = proxy.LogWhenAlways
 		params.Whatlog = proxy.LogWhatDetailed
@@ -98,9 +258,10 @@ func newProxyFromFlags() *proxy.Proxy {
 	// Azure: one scope must be supplied
 	// "offline_access" is used to request a refresh token
 	var scopes = []string{"offline_access", "api://285286f5-b97b-4b45-ba35-92a74f35756a/basic"}
-	if params.Method == proxy.MethodOAuthClientCredentials {
+	if params.Method == proxy.MethodOAuthClientCredentials || params.Method == proxy.MethodOAuthClientCertificate {
 		// See https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-client-creds-grant-flow
-		// this method should not provide a refresh token
+		// neither grant provides a refresh token, and both reject any
+		// scope that isn't a bare "<resource>/.default" (AADSTS1002012).
 		scopes = []string{"https://graph.microsoft.com/.default"}
 	}
 
@@ -125,6 +286,17 @@ func newProxyFromFlags() *proxy.Proxy {
 
 	checkStr(params.Local, params.Remote)
 
+	sink := logging.New(logging.Options{
+		Format:         logFormat,
+		RequestLogFile: logFile,
+		AuthLogFile:    authLogFile,
+		MaxSizeMB:      logMaxSize,
+		MaxAgeDays:     logMaxAge,
+		MaxBackups:     logMaxBackups,
+		Compress:       logCompress,
+	})
+	metricsRecorder := telemetry.NewRecorder()
+
 	p := (func() proxy.Provider {
 		switch params.Method {
 		case proxy.MethodOAuthAuthCode:
@@ -137,9 +309,13 @@ func newProxyFromFlags() *proxy.Proxy {
 					Scopes:       scopes,
 					RedirectURL:  redirectURL,
 				},
-				UseWebview: useWebview,
-				SvcAddr:    authSvcAddr,
-				ArgName:    flagAuthCodeWebview,
+				UseWebview:    useWebview,
+				SvcAddr:       authSvcAddr,
+				ArgName:       flagAuthCodeWebview,
+				CachePath:     params.TokenCachePath,
+				RefreshWindow: params.TokenRefreshWindow,
+				OnRefresh:     newAuthRecorder("OAuthAuthCode", sink, metricsRecorder),
+				OnCacheHit:    newCacheHitRecorder("OAuthAuthCode", sink, metricsRecorder),
 			}
 		case proxy.MethodOAuthClientCredentials:
 			checkStr(clientID, clientSecret)
@@ -151,6 +327,10 @@ func newProxyFromFlags() *proxy.Proxy {
 					Scopes:         scopes,
 					EndpointParams: nil,
 				},
+				CachePath:     params.TokenCachePath,
+				RefreshWindow: params.TokenRefreshWindow,
+				OnRefresh:     newAuthRecorder("OAuthClientCredentials", sink, metricsRecorder),
+				OnCacheHit:    newCacheHitRecorder("OAuthClientCredentials", sink, metricsRecorder),
 			}
 		case proxy.MethodOAuthDeviceFlow:
 			checkStr(clientID, tenantID)
@@ -160,6 +340,68 @@ func newProxyFromFlags() *proxy.Proxy {
 					ClientID: clientID,
 					Scopes:   scopes,
 				},
+				CachePath:     params.TokenCachePath,
+				RefreshWindow: params.TokenRefreshWindow,
+				OnRefresh:     newAuthRecorder("OAuthDeviceFlow", sink, metricsRecorder),
+				OnCacheHit:    newCacheHitRecorder("OAuthDeviceFlow", sink, metricsRecorder),
+			}
+		case proxy.MethodOAuthClientCertificate:
+			checkStr(clientID, tenantID)
+			signer, err := providers.NewKeySource(providers.KeySourceConfig{
+				Source:        keySource,
+				Algorithm:     keyAlgorithm,
+				KeyPath:       keyPath,
+				KeyPass:       keyPass,
+				KeyVaultKeyID: keyVaultKeyID,
+				CertPEMPath:   certPEMPath,
+				VaultCredential: &providers.ManagedIdentity{
+					Resource: "https://vault.azure.net",
+				},
+				PKCS11Module: pkcs11Module,
+				PKCS11PIN:    pkcs11PIN,
+				PKCS11Label:  pkcs11Label,
+			})
+			if err != nil {
+				fmt.Println("Failed to build client certificate signer:", err)
+				os.Exit(-1)
+			}
+			return &providers.OAuthClientCertificate{
+				TenantID: tenantID,
+				ClientID: clientID,
+				Scopes:   scopes,
+				Signer:   signer,
+			}
+		case proxy.MethodManagedIdentity:
+			return &providers.ManagedIdentity{
+				Resource:   msiResource,
+				ClientID:   clientID,
+				ObjectID:   msiObjectID,
+				ResourceID: msiResourceID,
+			}
+		case proxy.MethodWorkloadIdentity:
+			checkStr(clientID, tenantID)
+			return &providers.WorkloadIdentity{
+				TenantID:           tenantID,
+				ClientID:           clientID,
+				Resource:           msiResource,
+				FederatedTokenFile: federatedTokenFile,
+			}
+		case proxy.MethodDefault:
+			return &providers.Chained{
+				Providers: []proxy.Provider{
+					&providers.WorkloadIdentity{
+						TenantID:           tenantID,
+						ClientID:           clientID,
+						Resource:           msiResource,
+						FederatedTokenFile: federatedTokenFile,
+					},
+					&providers.ManagedIdentity{
+						Resource:   msiResource,
+						ClientID:   clientID,
+						ObjectID:   msiObjectID,
+						ResourceID: msiResourceID,
+					},
+				},
 			}
 		case proxy.MethodBasicAuth:
 			fallthrough
@@ -173,8 +415,82 @@ func newProxyFromFlags() *proxy.Proxy {
 		}
 	})()
 
+	telemetryShutdown, err := telemetry.Init(telemetry.Config{
+		ServiceName:  serviceName,
+		OTelEndpoint: otelEndpoint,
+		SampleRatio:  otelSampleRate,
+		MetricsAddr:  metricsAddr,
+	})
+	if err != nil {
+		fmt.Println("Failed to initialize telemetry:", err)
+		os.Exit(-1)
+	}
+	// There is no long-lived main() in this binary to defer the shutdown
+	// from, so flush traces and stop the metrics server ourselves on the
+	// process's termination signal.
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = telemetryShutdown(ctx)
+	}()
+
+	transport, err := proxy.NewTransport(proxy.TransportConfig{
+		HTTPVersion:           httpVersion,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		ExpectContinueTimeout: expectContinueTimeout,
+		ReadIdleTimeout:       http2ReadIdleTimeout,
+		PingTimeout:           http2PingTimeout,
+		CertPath:              params.CertPath,
+		Insecure:              params.Insecure,
+	})
+	if err != nil {
+		fmt.Println("Failed to build HTTP transport:", err)
+		os.Exit(-1)
+	}
+
 	return &proxy.Proxy{
-		Params:   params,
-		Provider: p,
+		Params:     params,
+		Provider:   p,
+		Logger:     sink,
+		AuthLogger: sink,
+		Metrics:    metricsRecorder,
+		Transport:  transport,
+	}
+}
+
+// newAuthRecorder builds the OnRefresh callback threaded into the OAuth
+// providers' TokenCache: every refresh attempt is recorded on both the
+// auth log stream and the auth metrics, labeled with the provider that
+// triggered it.
+func newAuthRecorder(providerName string, authLogger proxy.AuthLogger, metrics proxy.Metrics) func(time.Duration, error) {
+	return func(latency time.Duration, err error) {
+		entry := proxy.AuthLogEntry{
+			Provider: providerName,
+			Event:    "token_refresh",
+			Latency:  latency,
+			Err:      err,
+		}
+		authLogger.LogAuth(entry)
+		metrics.ObserveAuth(entry)
+	}
+}
+
+// newCacheHitRecorder builds the OnCacheHit callback threaded into the
+// OAuth providers' TokenCache: every time a token is served from cache
+// without a refresh attempt, it's recorded the same way newAuthRecorder
+// records a refresh, under a distinct event name.
+func newCacheHitRecorder(providerName string, authLogger proxy.AuthLogger, metrics proxy.Metrics) func() {
+	return func() {
+		entry := proxy.AuthLogEntry{
+			Provider: providerName,
+			Event:    "token_cache_hit",
+		}
+		authLogger.LogAuth(entry)
+		metrics.ObserveAuth(entry)
 	}
 }
@@ -0,0 +1,66 @@
+package proxy
+
+import "time"
+
+// RequestLogEntry carries the structured fields emitted for a single
+// proxied request. It is populated by Proxy.ServeHTTP and handed to the
+// configured Logger once the request/response cycle (or a failure) has
+// been fully observed.
+type RequestLogEntry struct {
+	CorrelationID string
+	Method        string
+	URL           string
+	Provider      string
+	StatusCode    int
+	Latency       time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	Retries       int
+	Err           error
+
+	// RequestBody and ResponseBody are only populated when Params.Whatlog
+	// is LogWhatDetailed, since capturing full bodies is expensive and
+	// can leak sensitive payloads into logs by default.
+	RequestBody  string
+	ResponseBody string
+}
+
+// AuthLogEntry carries the structured fields emitted for a provider
+// auth-related event, such as token acquisition or refresh.
+type AuthLogEntry struct {
+	Provider string
+	Event    string // e.g. "token_refresh", "token_cache_hit"
+	Latency  time.Duration
+	Err      error
+}
+
+// Logger receives one RequestLogEntry per proxied request. Implementations
+// decide whether, and in what form, to persist it; Proxy itself does not
+// apply any filtering beyond Params.Whenlog.
+type Logger interface {
+	LogRequest(entry RequestLogEntry)
+}
+
+// AuthLogger receives provider auth/token lifecycle events. It is kept
+// separate from Logger so operators can route the two streams to
+// different sinks (e.g. a SIEM-bound auth stream vs. a request-metrics
+// stream).
+type AuthLogger interface {
+	LogAuth(entry AuthLogEntry)
+}
+
+// shouldLog applies the Whenlog gate to a completed request.
+func shouldLog(whenlog string, statusCode int, err error) bool {
+	switch whenlog {
+	case LogWhenAlways:
+		return true
+	case LogWhenOnNon200:
+		return err != nil || statusCode != http200
+	case LogWhenOnError:
+		fallthrough
+	default:
+		return err != nil
+	}
+}
+
+const http200 = 200
@@ -0,0 +1,109 @@
+// Package logging provides the default proxy.Logger/proxy.AuthLogger
+// implementation: structured JSON (or plain text) events, optionally
+// rotated to disk via lumberjack, split across an independent request
+// stream and auth stream so operators can route each to its own file
+// or SIEM pipeline.
+package logging
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"io"
+	"os"
+
+	"github.com/natefinch/lumberjack"
+	"github.com/rs/zerolog"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Options configures the default Logger/AuthLogger pair returned by New.
+type Options struct {
+	// Format selects the wire format: FormatJSON (default) or FormatText.
+	Format string
+
+	// RequestLogFile is the destination for request-stream events. Empty
+	// means stdout.
+	RequestLogFile string
+	// AuthLogFile is the destination for auth-stream events. Empty means
+	// stderr, keeping it separate from the request stream by default.
+	AuthLogFile string
+
+	// Rotation settings, applied to both streams when a log file is set.
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+	Compress   bool
+}
+
+// Sink is the pair of loggers wired into proxy.Proxy.
+type Sink struct {
+	request zerolog.Logger
+	auth    zerolog.Logger
+}
+
+// New builds a Sink from opts. Each non-empty *LogFile is opened through
+// lumberjack so it rotates according to the MaxSize/MaxAge/MaxBackups/
+// Compress settings; an empty path falls back to the process's standard
+// streams.
+func New(opts Options) *Sink {
+	return &Sink{
+		request: newWriter(opts, opts.RequestLogFile, os.Stdout),
+		auth:    newWriter(opts, opts.AuthLogFile, os.Stderr),
+	}
+}
+
+func newWriter(opts Options, path string, fallback io.Writer) zerolog.Logger {
+	var w io.Writer = fallback
+	if path != "" {
+		w = &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxAge:     opts.MaxAgeDays,
+			MaxBackups: opts.MaxBackups,
+			Compress:   opts.Compress,
+		}
+	}
+	if opts.Format == FormatText {
+		w = zerolog.ConsoleWriter{Out: w, NoColor: true}
+	}
+	return zerolog.New(w).With().Timestamp().Logger()
+}
+
+// LogRequest implements proxy.Logger.
+func (s *Sink) LogRequest(entry proxy.RequestLogEntry) {
+	ev := s.request.Info()
+	if entry.Err != nil {
+		ev = s.request.Error().Err(entry.Err)
+	}
+	ev = ev.Str("correlation_id", entry.CorrelationID).
+		Str("method", entry.Method).
+		Str("url", entry.URL).
+		Str("provider", entry.Provider).
+		Int("status", entry.StatusCode).
+		Dur("latency", entry.Latency).
+		Int64("request_bytes", entry.RequestBytes).
+		Int64("response_bytes", entry.ResponseBytes).
+		Int("retries", entry.Retries)
+	if entry.RequestBody != "" {
+		ev = ev.Str("request_body", entry.RequestBody)
+	}
+	if entry.ResponseBody != "" {
+		ev = ev.Str("response_body", entry.ResponseBody)
+	}
+	ev.Msg("request")
+}
+
+// LogAuth implements proxy.AuthLogger.
+func (s *Sink) LogAuth(entry proxy.AuthLogEntry) {
+	ev := s.auth.Info()
+	if entry.Err != nil {
+		ev = s.auth.Error().Err(entry.Err)
+	}
+	ev.Str("provider", entry.Provider).
+		Str("event", entry.Event).
+		Dur("latency", entry.Latency).
+		Msg("auth")
+}
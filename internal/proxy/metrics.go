@@ -0,0 +1,27 @@
+package proxy
+
+// Metrics receives the same lifecycle events as Logger/AuthLogger, but is
+// expected to aggregate them into counters/histograms (e.g. Prometheus)
+// rather than emit one record per event.
+type Metrics interface {
+	IncInFlight()
+	DecInFlight()
+	ObserveRequest(entry RequestLogEntry)
+	ObserveAuth(entry AuthLogEntry)
+}
+
+// noopMetrics is used when Proxy.Metrics is nil, so instrumentation is
+// always safe to call unconditionally from ServeHTTP.
+type noopMetrics struct{}
+
+func (noopMetrics) IncInFlight()                   {}
+func (noopMetrics) DecInFlight()                   {}
+func (noopMetrics) ObserveRequest(RequestLogEntry) {}
+func (noopMetrics) ObserveAuth(AuthLogEntry)       {}
+
+func (p *Proxy) metrics() Metrics {
+	if p.Metrics != nil {
+		return p.Metrics
+	}
+	return noopMetrics{}
+}
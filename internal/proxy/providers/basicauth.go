@@ -0,0 +1,28 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"net/http"
+)
+
+// BasicAuth authenticates with a static HTTP Basic Authorization header,
+// the default (and simplest) method for remotes that don't speak AAD.
+type BasicAuth struct {
+	Remote   string
+	Username string
+	Password string
+}
+
+// RequestAccess is a no-op: the username/password pair doesn't expire or
+// need to be fetched.
+func (b *BasicAuth) RequestAccess() error { return nil }
+
+// Client returns an http.Client built on the shared transport.
+func (b *BasicAuth) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the configured Basic Authorization header.
+func (b *BasicAuth) Modify(params *proxy.Params, req *http.Request) {
+	req.SetBasicAuth(b.Username, b.Password)
+}
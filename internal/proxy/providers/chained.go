@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Chained tries each Provider in order and caches whichever one first
+// succeeds RequestAccess, mirroring the credential-chain semantics of the
+// Azure SDK's DefaultAzureCredential so the same binary works unmodified
+// across AKS pods (WorkloadIdentity), Azure VMs (ManagedIdentity) and
+// local/CI runs (e.g. OAuthClientCredentials).
+type Chained struct {
+	Providers []proxy.Provider
+
+	mu     sync.Mutex
+	active proxy.Provider
+}
+
+// RequestAccess tries each configured provider in order, returning the
+// first success and caching it for subsequent calls and for Client/Modify.
+// If none succeed, it returns an error combining every provider's failure.
+func (c *Chained) RequestAccess() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active != nil {
+		if err := c.active.RequestAccess(); err == nil {
+			return nil
+		}
+		c.active = nil
+	}
+
+	var errs []string
+	for _, p := range c.Providers {
+		if err := p.RequestAccess(); err != nil {
+			errs = append(errs, fmt.Sprintf("%T: %v", p, err))
+			continue
+		}
+		c.active = p
+		return nil
+	}
+	return fmt.Errorf("no provider in chain succeeded: %s", strings.Join(errs, "; "))
+}
+
+// Client delegates to the active provider, falling back to the first
+// configured provider if RequestAccess has not yet been called.
+func (c *Chained) Client(transport *http.Transport) *http.Client {
+	return c.current().Client(transport)
+}
+
+// Modify delegates to the active provider.
+func (c *Chained) Modify(params *proxy.Params, req *http.Request) {
+	c.current().Modify(params, req)
+}
+
+func (c *Chained) current() proxy.Provider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active != nil {
+		return c.active
+	}
+	if len(c.Providers) > 0 {
+		return c.Providers[0]
+	}
+	return nil
+}
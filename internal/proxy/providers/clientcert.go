@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"azure-blockchain-connector/aad"
+	"azure-blockchain-connector/internal/proxy"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// assertionLifetime is how long a signed client assertion JWT is valid
+// for; AAD rejects assertions with a longer nbf/exp span.
+const assertionLifetime = 5 * time.Minute
+
+// OAuthClientCertificate authenticates using the AAD certificate-based
+// client credentials flow: rather than sending a plaintext client
+// secret, it signs a client_assertion JWT (aud/iss/sub/jti/nbf/exp,
+// x5t#S256) with a Signer and exchanges it for an access token, so the
+// private key never needs to leave a local file, Key Vault or an HSM.
+type OAuthClientCertificate struct {
+	TenantID string
+	ClientID string
+	Scopes   []string
+	Signer   Signer
+
+	client *http.Client
+
+	mu    sync.Mutex
+	token string
+	exp   time.Time
+}
+
+// RequestAccess acquires (or refreshes, if the cached token is within a
+// minute of expiry) an access token via the client assertion grant.
+func (o *OAuthClientCertificate) RequestAccess() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && time.Now().Add(time.Minute).Before(o.exp) {
+		return nil
+	}
+
+	tokenEndpoint := aad.Endpoint(aad.EndpointToken, o.TenantID)
+	assertion, err := o.buildAssertion(tokenEndpoint)
+	if err != nil {
+		return fmt.Errorf("building client assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", o.ClientID)
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", assertion)
+	form.Set("scope", clientCredentialsScope(o.Scopes))
+
+	req, err := http.NewRequest(http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("building token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("decoding token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	o.token = tok.AccessToken
+	o.exp = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return nil
+}
+
+// buildAssertion signs a new client_assertion JWT for aud.
+func (o *OAuthClientCertificate) buildAssertion(aud string) (string, error) {
+	thumbprint, err := o.Signer.CertificateThumbprintSHA256()
+	if err != nil {
+		return "", fmt.Errorf("reading certificate thumbprint: %w", err)
+	}
+
+	header := map[string]interface{}{
+		"alg":      o.Signer.Algorithm(),
+		"typ":      "JWT",
+		"x5t#S256": base64.RawURLEncoding.EncodeToString(thumbprint),
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"aud": aud,
+		"iss": o.ClientID,
+		"sub": o.ClientID,
+		"jti": uuid.NewString(),
+		"nbf": now.Unix(),
+		"exp": now.Add(assertionLifetime).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := o.Signer.SignDigest(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing assertion: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// clientCredentialsScope builds the AAD-required "<resource>/.default"
+// scope for the client credentials grant, dropping the interactive-only
+// "offline_access" pseudo-scope (this grant issues no refresh token, and
+// AAD rejects it alongside a /.default scope with AADSTS1002012).
+func clientCredentialsScope(scopes []string) string {
+	var out []string
+	for _, s := range scopes {
+		if s == "offline_access" {
+			continue
+		}
+		out = append(out, defaultScope(s))
+	}
+	return strings.Join(out, " ")
+}
+
+func (o *OAuthClientCertificate) httpClient() *http.Client {
+	if o.client == nil {
+		o.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return o.client
+}
+
+// Client returns an http.Client built on the shared transport.
+func (o *OAuthClientCertificate) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (o *OAuthClientCertificate) Modify(params *proxy.Params, req *http.Request) {
+	o.mu.Lock()
+	token := o.token
+	o.mu.Unlock()
+	req.Header.Set("Authorization", "Bearer "+token)
+}
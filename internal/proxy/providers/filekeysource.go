@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// fileKeySource signs locally with an RSA private key loaded from a PEM
+// or PKCS#12 file on disk.
+type fileKeySource struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	alg  string
+}
+
+func newFileKeySource(path, pass, alg string) (*fileKeySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	var (
+		key  *rsa.PrivateKey
+		cert *x509.Certificate
+	)
+	if strings.HasSuffix(strings.ToLower(path), ".p12") || strings.HasSuffix(strings.ToLower(path), ".pfx") {
+		rawKey, rawCert, err := pkcs12.Decode(data, pass)
+		if err != nil {
+			return nil, fmt.Errorf("decoding PKCS#12: %w", err)
+		}
+		rsaKey, ok := rawKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("PKCS#12 private key is not RSA")
+		}
+		key, cert = rsaKey, rawCert
+	} else {
+		key, cert, err = parsePEM(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("no private key found in %s", path)
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+
+	return &fileKeySource{key: key, cert: cert, alg: alg}, nil
+}
+
+func parsePEM(data []byte) (*rsa.PrivateKey, *x509.Certificate, error) {
+	var (
+		key  *rsa.PrivateKey
+		cert *x509.Certificate
+	)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			cert = parsed
+		case "RSA PRIVATE KEY":
+			parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing PKCS#1 private key: %w", err)
+			}
+			key = parsed
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing PKCS#8 private key: %w", err)
+			}
+			rsaKey, ok := parsed.(*rsa.PrivateKey)
+			if !ok {
+				return nil, nil, fmt.Errorf("PKCS#8 private key is not RSA")
+			}
+			key = rsaKey
+		}
+	}
+	return key, cert, nil
+}
+
+func (f *fileKeySource) Algorithm() string { return f.alg }
+
+func (f *fileKeySource) CertificateThumbprintSHA256() ([]byte, error) {
+	sum := sha256.Sum256(f.cert.Raw)
+	return sum[:], nil
+}
+
+func (f *fileKeySource) SignDigest(digest []byte) ([]byte, error) {
+	if f.alg == "PS256" {
+		return rsa.SignPSS(rand.Reader, f.key, crypto.SHA256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	}
+	return rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest)
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// selfSignedPEM generates an RSA key, PKCS#8-encoded when pkcs8 is set
+// and PKCS#1-encoded otherwise, and a self-signed certificate for it,
+// PEM-encoded with the certificate block before the key block.
+func selfSignedPEM(t *testing.T, pkcs8 bool) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: certDER}); err != nil {
+		t.Fatalf("encoding certificate: %v", err)
+	}
+
+	if pkcs8 {
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("marshaling PKCS#8 key: %v", err)
+		}
+		if err := pem.Encode(&buf, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}); err != nil {
+			t.Fatalf("encoding private key: %v", err)
+		}
+	} else {
+		keyDER := x509.MarshalPKCS1PrivateKey(key)
+		if err := pem.Encode(&buf, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: keyDER}); err != nil {
+			t.Fatalf("encoding private key: %v", err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestParsePEM(t *testing.T) {
+	for _, pkcs8 := range []bool{false, true} {
+		data := selfSignedPEM(t, pkcs8)
+
+		key, cert, err := parsePEM(data)
+		if err != nil {
+			t.Fatalf("parsePEM() error: %v", err)
+		}
+		if key == nil {
+			t.Fatal("parsePEM() key = nil, want non-nil")
+		}
+		if cert == nil {
+			t.Fatal("parsePEM() cert = nil, want non-nil")
+		}
+		if cert.Subject.CommonName != "test" {
+			t.Fatalf("parsePEM() cert.Subject.CommonName = %q, want %q", cert.Subject.CommonName, "test")
+		}
+	}
+}
+
+func TestParsePEMEmpty(t *testing.T) {
+	key, cert, err := parsePEM([]byte("not a pem file"))
+	if err != nil {
+		t.Fatalf("parsePEM() unexpected error: %v", err)
+	}
+	if key != nil || cert != nil {
+		t.Fatalf("parsePEM() = (%v, %v), want (nil, nil)", key, cert)
+	}
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"fmt"
+)
+
+const (
+	KeySourceFile     = "file"     // local PEM/PKCS#12 private key
+	KeySourceKeyVault = "keyvault" // Azure Key Vault /sign REST API
+	KeySourcePKCS11   = "pkcs11"   // PKCS#11 HSM
+)
+
+// Signer performs the private-key operation behind an AAD client
+// assertion JWT. File-backed keys are loaded and sign in-process, while
+// Key Vault and PKCS#11 signers delegate to a remote API or HSM without
+// ever handing the private key itself to the proxy process.
+type Signer interface {
+	// Algorithm is the JWS alg this signer produces: "RS256" or "PS256".
+	Algorithm() string
+	// CertificateThumbprintSHA256 is the SHA-256 thumbprint of the
+	// signer's certificate, used as the JWT header's "x5t#S256".
+	CertificateThumbprintSHA256() ([]byte, error)
+	// SignDigest signs a SHA-256 digest of the JWT's signing input and
+	// returns the raw (not base64) signature bytes.
+	SignDigest(digest []byte) ([]byte, error)
+}
+
+// KeySourceConfig selects and configures one of the Signer
+// implementations backing OAuthClientCertificate.
+type KeySourceConfig struct {
+	// Source is one of KeySourceFile, KeySourceKeyVault or KeySourcePKCS11.
+	Source string
+	// Algorithm is the JWS alg to request: "RS256" (default) or "PS256".
+	Algorithm string
+
+	// KeySourceFile
+	KeyPath string // PEM or PKCS#12 file containing the private key + cert
+	KeyPass string // PKCS#12 passphrase, if any; encrypted PEM keys are not supported
+
+	// KeySourceKeyVault
+	// KeyVaultKeyID is the full key version URL, e.g.
+	// "https://myvault.vault.azure.net/keys/mykey/abcfeatured123".
+	KeyVaultKeyID string
+	// CertPEMPath supplies the public certificate for the thumbprint,
+	// since Key Vault's /sign API only returns a raw signature.
+	CertPEMPath string
+	// VaultCredential acquires the bearer token used to call Key Vault.
+	VaultCredential proxy.Provider
+
+	// KeySourcePKCS11
+	PKCS11Module string // path to the PKCS#11 provider shared library
+	PKCS11PIN    string
+	PKCS11Label  string // key label to sign with
+}
+
+// NewKeySource builds the Signer described by cfg.
+func NewKeySource(cfg KeySourceConfig) (Signer, error) {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = "RS256"
+	}
+
+	switch cfg.Source {
+	case KeySourceFile:
+		return newFileKeySource(cfg.KeyPath, cfg.KeyPass, alg)
+	case KeySourceKeyVault:
+		return newKeyVaultKeySource(cfg.KeyVaultKeyID, cfg.CertPEMPath, cfg.VaultCredential, alg)
+	case KeySourcePKCS11:
+		return newPKCS11KeySource(cfg.PKCS11Module, cfg.PKCS11PIN, cfg.PKCS11Label, alg)
+	default:
+		return nil, fmt.Errorf("unknown key source %q", cfg.Source)
+	}
+}
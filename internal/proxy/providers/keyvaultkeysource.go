@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// keyVaultKeySource signs by calling an Azure Key Vault key's /sign REST
+// API, so the private key material never leaves the vault/HSM backing
+// it. The public certificate is still read from disk, since /sign only
+// returns a signature, not the certificate needed for x5t#S256.
+type keyVaultKeySource struct {
+	keyID      string // full key version URL
+	cert       *x509.Certificate
+	credential proxy.Provider
+	alg        string
+
+	client *http.Client
+}
+
+func newKeyVaultKeySource(keyID, certPEMPath string, credential proxy.Provider, alg string) (*keyVaultKeySource, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("key vault key ID is required")
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("a credential to authenticate to Key Vault is required")
+	}
+
+	data, err := os.ReadFile(certPEMPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", certPEMPath)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	return &keyVaultKeySource{
+		keyID:      strings.TrimSuffix(keyID, "/"),
+		cert:       cert,
+		credential: credential,
+		alg:        alg,
+		client:     &http.Client{},
+	}, nil
+}
+
+func (k *keyVaultKeySource) Algorithm() string { return k.alg }
+
+func (k *keyVaultKeySource) CertificateThumbprintSHA256() ([]byte, error) {
+	sum := sha256.Sum256(k.cert.Raw)
+	return sum[:], nil
+}
+
+// SignDigest calls POST {keyID}/sign?api-version=7.4 with the digest to
+// sign and this source's configured algorithm.
+func (k *keyVaultKeySource) SignDigest(digest []byte) ([]byte, error) {
+	if err := k.credential.RequestAccess(); err != nil {
+		return nil, fmt.Errorf("authenticating to Key Vault: %w", err)
+	}
+
+	body, err := json.Marshal(struct {
+		Alg   string `json:"alg"`
+		Value string `json:"value"`
+	}{
+		Alg:   k.alg,
+		Value: base64.RawURLEncoding.EncodeToString(digest),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, k.keyID+"/sign?api-version=7.4", strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	k.credential.Modify(&proxy.Params{}, req)
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling Key Vault sign API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decoding Key Vault sign response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Value == "" {
+		return nil, fmt.Errorf("Key Vault sign API returned status %d", resp.StatusCode)
+	}
+
+	return base64.RawURLEncoding.DecodeString(out.Value)
+}
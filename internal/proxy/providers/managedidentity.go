@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const imdsTokenEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// ManagedIdentity authenticates via Azure's Managed Identity (MSI)
+// endpoint: IMDS on VMs/AKS nodes, or the IDENTITY_ENDPOINT/IDENTITY_HEADER
+// variant on App Service and Azure Functions. Exactly one of ClientID,
+// ObjectID or ResourceID should be set to select a user-assigned identity;
+// leaving all three empty selects the system-assigned identity.
+type ManagedIdentity struct {
+	// Resource is the resource URI the token is requested for, e.g.
+	// "https://management.azure.com/".
+	Resource string
+
+	// ClientID, ObjectID and ResourceID each select a specific
+	// user-assigned identity when set. At most one should be non-empty.
+	ClientID   string
+	ObjectID   string
+	ResourceID string
+
+	client *http.Client
+
+	mu    sync.Mutex
+	token string
+	exp   time.Time
+}
+
+// RequestAccess acquires (or refreshes, if the cached token is within a
+// minute of expiry) the identity's access token.
+func (m *ManagedIdentity) RequestAccess() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.token != "" && time.Now().Add(time.Minute).Before(m.exp) {
+		return nil
+	}
+
+	req, err := m.buildRequest()
+	if err != nil {
+		return fmt.Errorf("building managed identity token request: %w", err)
+	}
+
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting managed identity token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("decoding managed identity token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return fmt.Errorf("managed identity endpoint returned status %d", resp.StatusCode)
+	}
+
+	m.token = tok.AccessToken
+	m.exp = parseExpiry(tok.ExpiresOn, tok.ExpiresIn)
+	return nil
+}
+
+// parseExpiry derives the token's expiry from IMDS's expires_on (Unix
+// epoch seconds) field, falling back to expires_in (a duration in
+// seconds from now) and finally to a conservative 1 hour if both are
+// absent or malformed.
+func parseExpiry(expiresOn, expiresIn string) time.Time {
+	if expiresOn != "" {
+		if secs, err := strconv.ParseInt(expiresOn, 10, 64); err == nil {
+			return time.Unix(secs, 0)
+		}
+	}
+	if expiresIn != "" {
+		if secs, err := strconv.ParseInt(expiresIn, 10, 64); err == nil {
+			return time.Now().Add(time.Duration(secs) * time.Second)
+		}
+	}
+	return time.Now().Add(1 * time.Hour)
+}
+
+// buildRequest selects between the IMDS endpoint and the App
+// Service/Functions IDENTITY_ENDPOINT variant based on the presence of
+// the IDENTITY_ENDPOINT environment variable, matching the lookup order
+// DefaultAzureCredential uses.
+func (m *ManagedIdentity) buildRequest() (*http.Request, error) {
+	if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" {
+		q := url.Values{}
+		q.Set("resource", m.Resource)
+		q.Set("api-version", "2019-08-01")
+		m.setIdentityParam(q)
+
+		req, err := http.NewRequest(http.MethodGet, endpoint+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+		return req, nil
+	}
+
+	q := url.Values{}
+	q.Set("resource", m.Resource)
+	q.Set("api-version", "2018-02-01")
+	m.setIdentityParam(q)
+
+	req, err := http.NewRequest(http.MethodGet, imdsTokenEndpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	return req, nil
+}
+
+func (m *ManagedIdentity) setIdentityParam(q url.Values) {
+	switch {
+	case m.ClientID != "":
+		q.Set("client_id", m.ClientID)
+	case m.ObjectID != "":
+		q.Set("object_id", m.ObjectID)
+	case m.ResourceID != "":
+		q.Set("mi_res_id", m.ResourceID)
+	}
+}
+
+func (m *ManagedIdentity) httpClient() *http.Client {
+	if m.client == nil {
+		m.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return m.client
+}
+
+// Client returns an http.Client built on the shared transport. Token
+// acquisition against the IMDS endpoint uses its own short-lived client
+// instead, since IMDS is plain HTTP on a link-local address.
+func (m *ManagedIdentity) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (m *ManagedIdentity) Modify(params *proxy.Params, req *http.Request) {
+	m.mu.Lock()
+	token := m.token
+	m.mu.Unlock()
+	req.Header.Set("Authorization", "Bearer "+token)
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"azure-blockchain-connector/aad"
+	"azure-blockchain-connector/internal/proxy"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// authCodeTimeout bounds how long RequestAccess waits for the user to
+// complete sign-in in their browser before giving up.
+const authCodeTimeout = 5 * time.Minute
+
+// OAuthAuthCode authenticates via the AAD authorization code grant: the
+// user signs in through a browser and is redirected back to a local
+// callback listener bound to SvcAddr, which exchanges the code for a
+// token.
+type OAuthAuthCode struct {
+	Config *oauth2.Config
+
+	// UseWebview and ArgName are carried from the command line but are
+	// not read by the interactive flow below, which always prints the
+	// sign-in URL and never opens a webview.
+	UseWebview bool
+	SvcAddr    string
+	ArgName    string
+
+	// CachePath, when set, persists the encrypted token cache to disk so
+	// the user isn't re-prompted on every restart.
+	CachePath     string
+	RefreshWindow time.Duration
+	OnRefresh     func(latency time.Duration, err error)
+	OnCacheHit    func()
+
+	once    sync.Once
+	cache   *TokenCache
+	initErr error
+}
+
+func (o *OAuthAuthCode) ensureCache() error {
+	o.once.Do(func() {
+		ctx := context.Background()
+		seed, _ := loadToken(o.CachePath)
+
+		refreshToken := ""
+		if seed != nil && seed.RefreshToken != "" {
+			refreshToken = seed.RefreshToken
+		} else {
+			tok, err := o.authorize(ctx)
+			if err != nil {
+				o.initErr = err
+				return
+			}
+			refreshToken = tok.RefreshToken
+		}
+
+		// oauth2.Config.TokenSource caches internally with only a ~10s
+		// expiry buffer, which would silently defeat TokenCache's
+		// proactive RefreshWindow; force a real round trip every call
+		// and let TokenCache do the actual caching.
+		ts := aad.NewForceRefreshSource(ctx, o.Config, refreshToken)
+
+		cache := NewTokenCache(ts, o.CachePath, o.RefreshWindow)
+		cache.OnRefresh = o.OnRefresh
+		cache.OnCacheHit = o.OnCacheHit
+		cache.StartBackgroundRefresh(ctx)
+		o.cache = cache
+	})
+	return o.initErr
+}
+
+// authorize runs the interactive authorization code flow once: it opens
+// a local listener for the redirect, prints the sign-in URL, and waits
+// for the callback to deliver the code.
+func (o *OAuthAuthCode) authorize(ctx context.Context) (*oauth2.Token, error) {
+	state := uuid.NewString()
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+			errCh <- fmt.Errorf("authorization failed: %s", errMsg)
+			return
+		}
+		if r.URL.Query().Get("state") != state {
+			errCh <- fmt.Errorf("authorization callback: state mismatch")
+			return
+		}
+		codeCh <- r.URL.Query().Get("code")
+		fmt.Fprintln(w, "Authentication complete, you may close this window.")
+	})
+	srv := &http.Server{Addr: o.SvcAddr, Handler: mux}
+	go func() { _ = srv.ListenAndServe() }()
+	defer srv.Close()
+
+	fmt.Println("Open the following URL to sign in:")
+	fmt.Println(o.Config.AuthCodeURL(state))
+
+	select {
+	case code := <-codeCh:
+		return o.Config.Exchange(ctx, code)
+	case err := <-errCh:
+		return nil, err
+	case <-time.After(authCodeTimeout):
+		return nil, fmt.Errorf("timed out waiting for the authorization callback")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// RequestAccess runs the interactive authorization code flow (if no
+// cached refresh token is available) and acquires a token through the
+// cache.
+func (o *OAuthAuthCode) RequestAccess() error {
+	if err := o.ensureCache(); err != nil {
+		return err
+	}
+	_, err := o.cache.Token()
+	return err
+}
+
+// Client returns an http.Client built on the shared transport.
+func (o *OAuthAuthCode) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (o *OAuthAuthCode) Modify(params *proxy.Params, req *http.Request) {
+	if o.cache == nil {
+		return
+	}
+	tok, err := o.cache.Token()
+	if err != nil {
+		return
+	}
+	tok.SetAuthHeader(req)
+}
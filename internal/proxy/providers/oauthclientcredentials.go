@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuthClientCredentials authenticates via the AAD client credentials
+// grant: a client ID/secret pair is exchanged directly for an access
+// token, with no user interaction, suited to service-to-service calls.
+type OAuthClientCredentials struct {
+	Config *clientcredentials.Config
+
+	// CachePath, when set, persists the encrypted token cache to disk so
+	// a restart doesn't need a fresh token exchange.
+	CachePath string
+	// RefreshWindow is how far ahead of expiry the token is proactively
+	// refreshed. Zero uses TokenCache's default.
+	RefreshWindow time.Duration
+	// OnRefresh, if set, is called after every refresh attempt so the
+	// caller can record auth log/metric events.
+	OnRefresh func(latency time.Duration, err error)
+	// OnCacheHit, if set, is called whenever a token is served from the
+	// cache without a refresh attempt.
+	OnCacheHit func()
+
+	once  sync.Once
+	cache *TokenCache
+}
+
+func (o *OAuthClientCredentials) ensureCache() *TokenCache {
+	o.once.Do(func() {
+		cache := NewTokenCache(nonCachingSource{ctx: context.Background(), config: o.Config}, o.CachePath, o.RefreshWindow)
+		cache.OnRefresh = o.OnRefresh
+		cache.OnCacheHit = o.OnCacheHit
+		cache.StartBackgroundRefresh(context.Background())
+		o.cache = cache
+	})
+	return o.cache
+}
+
+// nonCachingSource performs a genuine client_credentials exchange on
+// every call. clientcredentials.Config.TokenSource returns a source that
+// caches internally with only a ~10s expiry buffer, which would
+// silently defeat TokenCache's proactive RefreshWindow; Config.Token
+// builds a fresh, uncached source each call instead, and TokenCache does
+// the actual caching.
+type nonCachingSource struct {
+	ctx    context.Context
+	config *clientcredentials.Config
+}
+
+func (s nonCachingSource) Token() (*oauth2.Token, error) {
+	return s.config.Token(s.ctx)
+}
+
+// RequestAccess acquires (or refreshes) a token through the cache.
+func (o *OAuthClientCredentials) RequestAccess() error {
+	_, err := o.ensureCache().Token()
+	return err
+}
+
+// Client returns an http.Client built on the shared transport.
+func (o *OAuthClientCredentials) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (o *OAuthClientCredentials) Modify(params *proxy.Params, req *http.Request) {
+	tok, err := o.ensureCache().Token()
+	if err != nil {
+		return
+	}
+	tok.SetAuthHeader(req)
+}
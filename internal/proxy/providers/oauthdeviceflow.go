@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"azure-blockchain-connector/aad/deviceflow"
+	"azure-blockchain-connector/internal/proxy"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OAuthDeviceFlow authenticates via the AAD device authorization grant,
+// for hosts with no local browser: the user signs in on another device
+// using a short code printed to stdout.
+type OAuthDeviceFlow struct {
+	Config *deviceflow.Config
+
+	// CachePath, when set, persists the encrypted token cache to disk so
+	// the user isn't re-prompted on every restart.
+	CachePath     string
+	RefreshWindow time.Duration
+	OnRefresh     func(latency time.Duration, err error)
+	OnCacheHit    func()
+
+	once    sync.Once
+	cache   *TokenCache
+	initErr error
+}
+
+func (o *OAuthDeviceFlow) ensureCache() error {
+	o.once.Do(func() {
+		ctx := context.Background()
+		seed, _ := loadToken(o.CachePath)
+
+		ts, err := o.Config.TokenSource(ctx, seed)
+		if err != nil {
+			o.initErr = err
+			return
+		}
+
+		cache := NewTokenCache(ts, o.CachePath, o.RefreshWindow)
+		cache.OnRefresh = o.OnRefresh
+		cache.OnCacheHit = o.OnCacheHit
+		cache.StartBackgroundRefresh(ctx)
+		o.cache = cache
+	})
+	return o.initErr
+}
+
+// RequestAccess runs the interactive device flow (if no cached refresh
+// token is available) and acquires a token through the cache.
+func (o *OAuthDeviceFlow) RequestAccess() error {
+	if err := o.ensureCache(); err != nil {
+		return err
+	}
+	_, err := o.cache.Token()
+	return err
+}
+
+// Client returns an http.Client built on the shared transport.
+func (o *OAuthDeviceFlow) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (o *OAuthDeviceFlow) Modify(params *proxy.Params, req *http.Request) {
+	if o.cache == nil {
+		return
+	}
+	tok, err := o.cache.Token()
+	if err != nil {
+		return
+	}
+	tok.SetAuthHeader(req)
+}
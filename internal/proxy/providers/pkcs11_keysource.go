@@ -0,0 +1,131 @@
+//go:build pkcs11
+
+package providers
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/miekg/pkcs11"
+)
+
+// pkcs11KeySource signs using a private key held in a PKCS#11 HSM,
+// identified by label. Built only with `-tags pkcs11`, since it links
+// against a vendor-provided PKCS#11 shared library via cgo.
+type pkcs11KeySource struct {
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	privKey pkcs11.ObjectHandle
+	cert    *x509.Certificate
+	alg     string
+}
+
+func newPKCS11KeySource(modulePath, pin, label, alg string) (*pkcs11KeySource, error) {
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("loading PKCS#11 module %s", modulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		return nil, fmt.Errorf("initializing PKCS#11 module: %w", err)
+	}
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil || len(slots) == 0 {
+		return nil, fmt.Errorf("listing PKCS#11 slots: %w", err)
+	}
+
+	session, err := ctx.OpenSession(slots[0], pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return nil, fmt.Errorf("opening PKCS#11 session: %w", err)
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		return nil, fmt.Errorf("logging in to PKCS#11 token: %w", err)
+	}
+
+	privKey, cert, err := findKeyAndCert(ctx, session, label)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkcs11KeySource{ctx: ctx, session: session, privKey: privKey, cert: cert, alg: alg}, nil
+}
+
+func findKeyAndCert(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, label string) (pkcs11.ObjectHandle, *x509.Certificate, error) {
+	keyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := ctx.FindObjectsInit(session, keyTemplate); err != nil {
+		return 0, nil, fmt.Errorf("finding PKCS#11 private key: %w", err)
+	}
+	keys, _, err := ctx.FindObjects(session, 1)
+	_ = ctx.FindObjectsFinal(session)
+	if err != nil || len(keys) == 0 {
+		return 0, nil, fmt.Errorf("no PKCS#11 private key found with label %q", label)
+	}
+
+	certTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_CERTIFICATE),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := ctx.FindObjectsInit(session, certTemplate); err != nil {
+		return 0, nil, fmt.Errorf("finding PKCS#11 certificate: %w", err)
+	}
+	certObjs, _, err := ctx.FindObjects(session, 1)
+	_ = ctx.FindObjectsFinal(session)
+	if err != nil || len(certObjs) == 0 {
+		return 0, nil, fmt.Errorf("no PKCS#11 certificate found with label %q", label)
+	}
+
+	attrs, err := ctx.GetAttributeValue(session, certObjs[0], []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil || len(attrs) == 0 {
+		return 0, nil, fmt.Errorf("reading PKCS#11 certificate value: %w", err)
+	}
+	cert, err := x509.ParseCertificate(attrs[0].Value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parsing PKCS#11 certificate: %w", err)
+	}
+
+	return keys[0], cert, nil
+}
+
+func (p *pkcs11KeySource) Algorithm() string { return p.alg }
+
+func (p *pkcs11KeySource) CertificateThumbprintSHA256() ([]byte, error) {
+	sum := sha256.Sum256(p.cert.Raw)
+	return sum[:], nil
+}
+
+// sha256DigestInfoPrefix is the DER encoding of the SHA-256
+// AlgorithmIdentifier prepended to the digest in a PKCS#1 v1.5
+// signature (RFC 8017 9.2), the same table crypto/rsa embeds
+// internally. CKM_RSA_PKCS only applies the PKCS#1 v1.5 padding, not
+// this DigestInfo wrapper, so it must be prepended by the caller for
+// the result to be a valid RS256 JWS signature.
+var sha256DigestInfoPrefix = []byte{
+	0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20,
+}
+
+func (p *pkcs11KeySource) SignDigest(digest []byte) ([]byte, error) {
+	var (
+		mechanism *pkcs11.Mechanism
+		toSign    []byte
+	)
+	if p.alg == "PS256" {
+		// CK_RSA_PKCS_PSS_PARAMS: SHA-256 hash, MGF1-SHA256, salt length
+		// equal to the hash length, matching filekeysource's PSSSaltLengthEqualsHash.
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256, uint(len(digest))))
+		toSign = digest
+	} else {
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		toSign = append(append([]byte{}, sha256DigestInfoPrefix...), digest...)
+	}
+
+	if err := p.ctx.SignInit(p.session, []*pkcs11.Mechanism{mechanism}, p.privKey); err != nil {
+		return nil, fmt.Errorf("initializing PKCS#11 sign: %w", err)
+	}
+	return p.ctx.Sign(p.session, toSign)
+}
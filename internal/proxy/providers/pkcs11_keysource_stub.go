@@ -0,0 +1,12 @@
+//go:build !pkcs11
+
+package providers
+
+import "fmt"
+
+// newPKCS11KeySource is stubbed out in default builds, since PKCS#11
+// support links against a vendor shared library via cgo. Build with
+// `-tags pkcs11` to enable it.
+func newPKCS11KeySource(modulePath, pin, label, alg string) (Signer, error) {
+	return nil, fmt.Errorf("built without PKCS#11 support: rebuild with -tags pkcs11")
+}
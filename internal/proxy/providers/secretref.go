@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"azure-blockchain-connector/internal/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const keyVaultSecretRefPrefix = "keyvault://"
+
+// ResolveSecretRef resolves a -client-secret-ref value. A
+// "keyvault://<vault>/<secret>[/<version>]" ref is fetched from Azure
+// Key Vault (authenticating via the ambient managed identity) so the
+// secret never needs to be passed in plaintext on the command line or
+// left in shell history; any other value is returned unchanged.
+func ResolveSecretRef(ref string) (string, error) {
+	if !strings.HasPrefix(ref, keyVaultSecretRefPrefix) {
+		return ref, nil
+	}
+
+	vault, name, version, err := parseKeyVaultRef(strings.TrimPrefix(ref, keyVaultSecretRefPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	cred := &ManagedIdentity{Resource: "https://vault.azure.net"}
+	if err := cred.RequestAccess(); err != nil {
+		return "", fmt.Errorf("authenticating to Key Vault: %w", err)
+	}
+
+	url := fmt.Sprintf("https://%s.vault.azure.net/secrets/%s", vault, name)
+	if version != "" {
+		url += "/" + version
+	}
+	url += "?api-version=7.4"
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	cred.Modify(&proxy.Params{}, req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching secret from Key Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decoding Key Vault secret response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Value == "" {
+		return "", fmt.Errorf("Key Vault returned status %d for secret %q", resp.StatusCode, name)
+	}
+	return out.Value, nil
+}
+
+func parseKeyVaultRef(rest string) (vault, name, version string, err error) {
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 {
+		return "", "", "", fmt.Errorf("invalid keyvault:// ref %q, expected keyvault://<vault>/<secret>[/<version>]", rest)
+	}
+	vault, name = parts[0], parts[1]
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+	return vault, name, version, nil
+}
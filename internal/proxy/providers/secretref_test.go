@@ -0,0 +1,57 @@
+package providers
+
+import "testing"
+
+func TestParseKeyVaultRef(t *testing.T) {
+	cases := []struct {
+		name        string
+		rest        string
+		wantVault   string
+		wantSecret  string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name:       "vault and secret only",
+			rest:       "myvault/mysecret",
+			wantVault:  "myvault",
+			wantSecret: "mysecret",
+		},
+		{
+			name:        "vault, secret and version",
+			rest:        "myvault/mysecret/abc123",
+			wantVault:   "myvault",
+			wantSecret:  "mysecret",
+			wantVersion: "abc123",
+		},
+		{
+			name:    "missing secret",
+			rest:    "myvault",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			rest:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vault, name, version, err := parseKeyVaultRef(c.rest)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("parseKeyVaultRef(%q) error = nil, want error", c.rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKeyVaultRef(%q) unexpected error: %v", c.rest, err)
+			}
+			if vault != c.wantVault || name != c.wantSecret || version != c.wantVersion {
+				t.Fatalf("parseKeyVaultRef(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					c.rest, vault, name, version, c.wantVault, c.wantSecret, c.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultRefreshWindow is how far ahead of expiry TokenCache proactively
+// refreshes a token, keeping refreshes off the request path.
+const defaultRefreshWindow = 5 * time.Minute
+
+// TokenCache wraps an oauth2.TokenSource (as used by OAuthAuthCode,
+// OAuthClientCredentials and OAuthDeviceFlow) with an on-disk, encrypted
+// cache and proactive background refresh, so device-flow users aren't
+// prompted to re-authenticate on every restart and a burst of proxied
+// requests never triggers duplicate AAD calls.
+type TokenCache struct {
+	Source oauth2.TokenSource
+
+	// Path is where the encrypted token is persisted. Empty disables
+	// persistence; the cache still dedupes concurrent refreshes and
+	// refreshes proactively in memory.
+	Path string
+
+	// RefreshWindow is how far ahead of expiry a cached token is
+	// considered due for refresh. NewTokenCache defaults it to 5 minutes.
+	RefreshWindow time.Duration
+
+	// OnRefresh, if set, is called after every refresh attempt (success
+	// or failure) with how long it took, so callers can record
+	// refresh metrics (e.g. via proxy.Metrics.ObserveAuth).
+	OnRefresh func(latency time.Duration, err error)
+
+	// OnCacheHit, if set, is called whenever Token() is served from the
+	// in-memory cache without a refresh attempt, so callers can record a
+	// cache-hit metric alongside OnRefresh's refresh-attempt metric.
+	OnCacheHit func()
+
+	sf     singleflight.Group
+	mu     sync.Mutex
+	cached *oauth2.Token
+	loaded bool
+}
+
+// NewTokenCache returns a TokenCache wrapping source, persisting to path
+// (if non-empty) and refreshing proactively within window of expiry.
+func NewTokenCache(source oauth2.TokenSource, path string, window time.Duration) *TokenCache {
+	if window <= 0 {
+		window = defaultRefreshWindow
+	}
+	return &TokenCache{Source: source, Path: path, RefreshWindow: window}
+}
+
+// Token implements oauth2.TokenSource. It returns the cached token when
+// it is valid and outside the refresh window, otherwise it refreshes
+// (coalescing concurrent callers via singleflight) and persists the
+// result.
+func (c *TokenCache) Token() (*oauth2.Token, error) {
+	if tok := c.cachedToken(); tok != nil && tok.Valid() && time.Until(tok.Expiry) > c.RefreshWindow {
+		if c.OnCacheHit != nil {
+			c.OnCacheHit()
+		}
+		return tok, nil
+	}
+
+	v, err, _ := c.sf.Do("refresh", func() (interface{}, error) {
+		start := time.Now()
+		tok, err := c.Source.Token()
+		if c.OnRefresh != nil {
+			c.OnRefresh(time.Since(start), err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.setCached(tok)
+		if c.Path != "" {
+			if perr := persistToken(c.Path, tok); perr != nil {
+				return tok, fmt.Errorf("persisting cached token: %w", perr)
+			}
+		}
+		return tok, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*oauth2.Token), nil
+}
+
+// StartBackgroundRefresh starts a goroutine that polls every
+// RefreshWindow/2 and refreshes the token ahead of expiry so the request
+// path never blocks on a refresh. It returns immediately; the goroutine
+// stops once ctx is done.
+func (c *TokenCache) StartBackgroundRefresh(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.RefreshWindow / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if tok := c.cachedToken(); tok == nil || time.Until(tok.Expiry) <= c.RefreshWindow {
+					_, _ = c.Token()
+				}
+			}
+		}
+	}()
+}
+
+func (c *TokenCache) cachedToken() *oauth2.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		c.loaded = true
+		if c.Path != "" {
+			if tok, err := loadToken(c.Path); err == nil {
+				c.cached = tok
+			}
+		}
+	}
+	return c.cached
+}
+
+func (c *TokenCache) setCached(tok *oauth2.Token) {
+	c.mu.Lock()
+	c.cached = tok
+	c.loaded = true
+	c.mu.Unlock()
+}
+
+func loadToken(path string) (*oauth2.Token, error) {
+	ciphertext, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decrypt(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting token cache: %w", err)
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(plaintext, &tok); err != nil {
+		return nil, fmt.Errorf("unmarshaling token cache: %w", err)
+	}
+	return &tok, nil
+}
+
+func persistToken(path string, tok *oauth2.Token) error {
+	plaintext, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, ciphertext, 0o600)
+}
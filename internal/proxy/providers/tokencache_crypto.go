@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "azure-blockchain-connector"
+	keyringUser    = "token-cache-key"
+	keySize        = 32 // AES-256
+)
+
+// encryptionKey returns the AES-256 key used to seal the on-disk token
+// cache, generating and storing one in the OS keychain on first use so
+// the cache can't be read by copying the file alone.
+func encryptionKey() ([]byte, error) {
+	hexKey, err := keyring.Get(keyringService, keyringUser)
+	if err == nil {
+		return hex.DecodeString(hexKey)
+	}
+	if err != keyring.ErrNotFound {
+		return nil, fmt.Errorf("reading token cache key from keychain: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating token cache key: %w", err)
+	}
+	if err := keyring.Set(keyringService, keyringUser, hex.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("storing token cache key in keychain: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD() (cipher.AEAD, error) {
+	key, err := encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt seals plaintext with AES-GCM, prefixing the ciphertext with a
+// random nonce.
+func encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := newAEAD()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decrypt reverses encrypt.
+func decrypt(data []byte) ([]byte, error) {
+	gcm, err := newAEAD()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext shorter than nonce")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
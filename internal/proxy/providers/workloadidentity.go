@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"azure-blockchain-connector/aad"
+	"azure-blockchain-connector/internal/proxy"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const federatedAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// WorkloadIdentity authenticates via Azure AD Workload Identity
+// federation: the Kubernetes-projected service account token named by
+// AZURE_FEDERATED_TOKEN_FILE is exchanged for an AAD access token using
+// the client_assertion/jwt-bearer grant, so AKS pods never hold a
+// long-lived client secret.
+type WorkloadIdentity struct {
+	TenantID           string
+	ClientID           string
+	Resource           string
+	FederatedTokenFile string // defaults to $AZURE_FEDERATED_TOKEN_FILE
+
+	client *http.Client
+
+	mu    sync.Mutex
+	token string
+	exp   time.Time
+}
+
+// RequestAccess exchanges the projected service account token for an
+// AAD access token, refreshing it if the cached one is within a minute
+// of expiry.
+func (w *WorkloadIdentity) RequestAccess() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.token != "" && time.Now().Add(time.Minute).Before(w.exp) {
+		return nil
+	}
+
+	assertion, err := w.readFederatedToken()
+	if err != nil {
+		return fmt.Errorf("reading federated token: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", w.ClientID)
+	form.Set("client_assertion_type", federatedAssertionType)
+	form.Set("client_assertion", assertion)
+	form.Set("scope", defaultScope(w.Resource))
+
+	req, err := http.NewRequest(http.MethodPost, aad.Endpoint(aad.EndpointToken, w.TenantID), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("building token exchange request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := w.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("exchanging federated token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("decoding token exchange response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	w.token = tok.AccessToken
+	w.exp = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return nil
+}
+
+// defaultScope turns a bare resource URI into the "<resource>/.default"
+// form the v2 AAD endpoint requires for the client_credentials grant
+// (AADSTS1002012 otherwise), leaving an already-scoped value untouched.
+func defaultScope(resource string) string {
+	if strings.HasSuffix(resource, "/.default") {
+		return resource
+	}
+	return strings.TrimSuffix(resource, "/") + "/.default"
+}
+
+func (w *WorkloadIdentity) readFederatedToken() (string, error) {
+	path := w.FederatedTokenFile
+	if path == "" {
+		path = os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	}
+	if path == "" {
+		return "", fmt.Errorf("AZURE_FEDERATED_TOKEN_FILE is not set and FederatedTokenFile is empty")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (w *WorkloadIdentity) httpClient() *http.Client {
+	if w.client == nil {
+		w.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return w.client
+}
+
+// Client returns an http.Client built on the shared transport.
+func (w *WorkloadIdentity) Client(transport *http.Transport) *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// Modify attaches the cached bearer token to the outbound request.
+func (w *WorkloadIdentity) Modify(params *proxy.Params, req *http.Request) {
+	w.mu.Lock()
+	token := w.token
+	w.mu.Unlock()
+	req.Header.Set("Authorization", "Bearer "+token)
+}
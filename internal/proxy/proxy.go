@@ -2,14 +2,27 @@ package proxy
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
-	"net"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"compress/gzip"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName identifies this package's spans in the configured OTel
+// TracerProvider; it has no relation to the proxied service name.
+const tracerName = "azure-blockchain-connector/internal/proxy"
+
 const (
 	LogWhenOnError  = "onError"  // print log only for those who raise exceptions
 	LogWhenOnNon200 = "onNon200" // print log for those who have a non-200 response, or those who raise exceptions
@@ -18,6 +31,18 @@ const (
 	LogWhatBasic    = "basic"    // print the request's method and URI and the response status code (and the exception message, if exception raised) in the log
 	LogWhatDetailed = "detailed" // print the request's method, URI and body, and the response status code and body (and the exception message, if exception raised) in the log
 	//LogAll          = "all"      // to be supported later. Compared to whatlog_detail, all Headers are printed in whatlog_all
+
+	MethodBasicAuth              = "basic"
+	MethodOAuthAuthCode          = "authcode"
+	MethodOAuthDeviceFlow        = "device"
+	MethodOAuthClientCredentials = "client"
+	MethodManagedIdentity        = "msi"
+	MethodWorkloadIdentity       = "workload"
+	MethodOAuthClientCertificate = "cert"
+	// MethodDefault chains WorkloadIdentity then ManagedIdentity, mirroring
+	// the Azure SDK's DefaultAzureCredential so the same binary works
+	// unmodified across AKS pods and Azure VMs.
+	MethodDefault = "default"
 )
 
 type Params struct {
@@ -30,21 +55,82 @@ type Params struct {
 
 	Whenlog string
 	Whatlog string
+
+	// TokenCachePath, when set, is where OAuth-based providers persist
+	// their encrypted token cache (see providers.TokenCache).
+	TokenCachePath string
+	// TokenRefreshWindow is how far ahead of expiry OAuth-based providers
+	// proactively refresh a cached token.
+	TokenRefreshWindow time.Duration
+
+	// The Retry* fields configure how ServeHTTP retries a failed upstream call. A
+	// zero-value RetryMax disables retries, preserving the historical
+	// single-attempt behavior.
+	RetryMax         int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	RetryStatusCodes []int
 }
 
 type Provider interface {
 	RequestAccess() error
-	Client() *http.Client
+	// Client returns the *http.Client used to call the proxied remote.
+	// transport is the single shared, pre-configured transport built
+	// from Params; implementations should build their client around it
+	// rather than constructing their own, so connections (and HTTP/2
+	// streams) are reused across providers and requests.
+	Client(transport *http.Transport) *http.Client
 	Modify(params *Params, req *http.Request)
 }
 
 type Proxy struct {
 	*Params
-	Provider Provider
+	Provider   Provider
+	Logger     Logger
+	AuthLogger AuthLogger
+	Metrics    Metrics
+	Transport  *http.Transport
+}
+
+// logger returns the configured Logger, falling back to a Logger that
+// reproduces the historical fmt.Println behavior so a zero-value Proxy
+// (e.g. in tests) keeps working without a Logger wired in.
+func (p *Proxy) logger() Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return stdoutLogger{}
 }
 
 func (p *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	var params = p.Params
+	start := time.Now()
+	correlationID := req.Header.Get("X-Correlation-ID")
+	if correlationID == "" {
+		correlationID = uuid.NewString()
+	}
+
+	provider := providerType(p.Provider)
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "proxy.ServeHTTP",
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("net.sock.peer.addr", req.RemoteAddr),
+			attribute.String("net.peer.name", params.Remote),
+			attribute.String("proxy.provider", provider),
+			attribute.String("proxy.correlation_id", correlationID),
+		),
+	)
+	req = req.WithContext(ctx)
+	defer span.End()
+
+	p.metrics().IncInFlight()
+	defer p.metrics().DecInFlight()
+
+	entry := RequestLogEntry{
+		CorrelationID: correlationID,
+		Method:        req.Method,
+		Provider:      provider,
+	}
 
 	//completeFlag indicates if the server has finished constructing the response. It's initialized with value false.
 	//It will be set to true when response construction finished.
@@ -59,83 +145,82 @@ func (p *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		}
 	}(&completeFlag, &rw)
 
-	// logFlag is initialized with value true.
-	// it will be set false if our program finally ensure it's not needed to print the log (depends on the running state and params.whenlog).
-	// when ServeHTTP finished (or crashed), if logFlag remains true, log will be printed
-	logFlag := true
+	// Notice that here the func in defer is needed!
+	// By doing so, defer will register the pointer entry and flag, and we can change what they point to later.
+	// Without the func, defer will know nothing about the later changes to entry and completeFlag.
+	defer func(entry *RequestLogEntry, flag *bool) {
+		entry.Latency = time.Since(start)
+		if !*flag && entry.Err == nil {
+			entry.Err = fmt.Errorf("request did not complete")
+		}
 
-	logStrBuilder := new(strings.Builder)
+		span.SetAttributes(
+			attribute.Int("http.status_code", entry.StatusCode),
+			attribute.Int("proxy.retries", entry.Retries),
+		)
+		if entry.Err != nil {
+			span.RecordError(entry.Err)
+			span.SetStatus(codes.Error, entry.Err.Error())
+		}
 
-	// Notice that here the func in defer is needed!
-	// By doing so, defer will register the pointer strBuilder and flag, and we can change what the pointers point to later.
-	// Without the func, what defer registers is not the pointers, and defer will know nothing about the later changes to stringbulider and flag.
-	defer func(strBuilder *strings.Builder, flag *bool) {
-		if *flag {
-			fmt.Println(strBuilder.String())
+		p.metrics().ObserveRequest(*entry)
+		if shouldLog(params.Whenlog, entry.StatusCode, entry.Err) {
+			p.logger().LogRequest(*entry)
 		}
-	}(logStrBuilder, &logFlag)
+	}(&entry, &completeFlag)
+
+	reqBuf := new(bytes.Buffer)
+	_, _ = reqBuf.ReadFrom(req.Body)
+	requestBody := reqBuf.Bytes()
+	entry.RequestBytes = int64(len(requestBody))
+	if params.Whatlog == LogWhatDetailed {
+		entry.RequestBody = string(requestBody)
+	}
 
-	buf := new(bytes.Buffer)
-	_, _ = buf.ReadFrom(req.Body)
-	
 	req.URL.Host = params.Remote
 	req.URL.Scheme = "https"
 
 	if isLoopbackAddr(req.URL.Host) {
 		req.URL.Scheme = "http"
 	}
+	entry.URL = req.URL.String()
 
-	logStrBuilder.WriteString(fmt.Sprintln("Requesting:", req.Method, req.URL))
-	if params.Whatlog >= LogWhatDetailed {
-		logStrBuilder.WriteString(buf.String() + "\n")
-	}
-
-	// build the transport request
-	req1, err := http.NewRequest(req.Method, req.URL.String(), buf)
-	if err != nil {
-		logStrBuilder.WriteString(fmt.Sprintln("Error when make transport request:\n", err))
+	if err := p.Provider.RequestAccess(); err != nil {
+		entry.Err = fmt.Errorf("acquiring provider access: %w", err)
 		return
 	}
-	req1.ContentLength = req.ContentLength
-	req1.Header = req.Header
-	req1.Method = req.Method
-
-	p.Provider.Modify(params, req1)
-	//req1.SetBasicAuth(params.Username, params.Password)
 
-	// do request and get response
-	response, err := p.Provider.Client().Do(req1)
+	response, err := p.doWithRetry(ctx, req, requestBody, &entry)
 	if err != nil {
-		logStrBuilder.WriteString(fmt.Sprintln("Error when send the transport request:\n", err))
+		entry.Err = err
 		return
 	}
 	defer response.Body.Close()
-	buf = new(bytes.Buffer)
-	
-	// Add GZIP support 
+
+	buf := new(bytes.Buffer)
+	// Add GZIP support
 	switch response.Header.Get("Content-Encoding") {
 	case "gzip":
 		reader, err := gzip.NewReader(response.Body)
 		if err != nil {
-			logStrBuilder.WriteString(fmt.Sprintln("Error while decoding gzip data:\n", err))
-			return 
+			entry.Err = fmt.Errorf("decoding gzip response: %w", err)
+			return
 		}
 		defer reader.Close()
 		data, err := ioutil.ReadAll(reader)
 		if err != nil {
-			logStrBuilder.WriteString(fmt.Sprintln("Error while reading gzip data:\n", err))
-			return 
+			entry.Err = fmt.Errorf("reading gzip response: %w", err)
+			return
 		}
 		buf.Write(data)
 	default:
 		_, _ = buf.ReadFrom(response.Body)
 	}
-	
 
-	logStrBuilder.WriteString(fmt.Sprintln("Response Status Code:", response.StatusCode))
-
-	if params.Whatlog >= LogWhatDetailed {
-		logStrBuilder.WriteString(fmt.Sprintln(buf.String()))
+	entry.StatusCode = response.StatusCode
+	entry.ResponseBytes = int64(buf.Len())
+	if params.Whatlog == LogWhatDetailed {
+		entry.ResponseBody = buf.String()
 	}
 
 	rw.WriteHeader(response.StatusCode)
@@ -143,15 +228,89 @@ func (p *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	//Set completeFlag to indicate that the response construction finished
 	completeFlag = true
+}
 
-	// check if logFlag should be set to false
-	if params.Whenlog == LogWhenOnError {
-		logFlag = false
-	}
-	if params.Whenlog == LogWhenOnNon200 {
-		if response.StatusCode == 200 {
-			logFlag = false
+// doWithRetry builds the transport request and sends it, retrying on
+// transport errors and configured retryable status codes according to
+// params.Retry*, following full-jitter exponential backoff and honoring
+// any Retry-After/x-ms-retry-after-ms the upstream returns. It gives up
+// early if ctx is done.
+func (p *Proxy) doWithRetry(ctx context.Context, req *http.Request, requestBody []byte, entry *RequestLogEntry) (*http.Response, error) {
+	params := p.Params
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req1, err := http.NewRequestWithContext(ctx, req.Method, req.URL.String(), bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, fmt.Errorf("building transport request: %w", err)
+		}
+		req1.ContentLength = req.ContentLength
+		req1.Header = req.Header.Clone()
+
+		// propagate traceparent/tracestate so the Azure Blockchain endpoint
+		// (or any intermediary) can join this trace.
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req1.Header))
+
+		p.Provider.Modify(params, req1)
+		//req1.SetBasicAuth(params.Username, params.Password)
+
+		response, err := p.Provider.Client(p.Transport).Do(req1)
+		entry.Retries = attempt
+
+		statusCode := 0
+		var retryHeader http.Header
+		if response != nil {
+			statusCode = response.StatusCode
+			retryHeader = response.Header
+		}
+
+		if err == nil {
+			lastErr = nil
+		} else {
+			lastErr = fmt.Errorf("sending transport request: %w", err)
 		}
+
+		if attempt >= params.RetryMax || !shouldRetry(params, req.Method, int64(len(requestBody)), statusCode, err) {
+			if err != nil {
+				return nil, lastErr
+			}
+			return response, nil
+		}
+
+		if response != nil {
+			_ = response.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return nil, lastErr
+		case <-time.After(nextDelay(params, attempt, retryHeader)):
+		}
+	}
+}
+
+// providerType names the concrete Provider implementation for log/metric
+// attribution, e.g. "BasicAuth" or "OAuthClientCredentials".
+func providerType(p Provider) string {
+	t := fmt.Sprintf("%T", p)
+	if i := strings.LastIndexByte(t, '.'); i >= 0 {
+		return t[i+1:]
+	}
+	return t
+}
+
+// stdoutLogger is the fallback Logger used when Proxy.Logger is nil,
+// preserving the historical one-line-per-request console output.
+type stdoutLogger struct{}
+
+func (stdoutLogger) LogRequest(entry RequestLogEntry) {
+	fmt.Println("Requesting:", entry.Method, entry.URL)
+	fmt.Println("Response Status Code:", entry.StatusCode)
+	if entry.Err != nil {
+		fmt.Println("Error:", entry.Err)
 	}
 }
 
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryableMethod reports whether method is safe to retry on its own,
+// independent of whether the request carried a body: idempotent methods
+// per RFC 7231.
+func retryableMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryableStatus reports whether statusCode is configured as retryable.
+func retryableStatus(codes []int, statusCode int) bool {
+	for _, c := range codes {
+		if c == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry decides whether another attempt should be made, following
+// the same rule HTTP clients commonly apply: retry idempotent methods,
+// or any request whose body is empty (so replaying it is side-effect
+// free), on transport errors or a configured retryable status code.
+func shouldRetry(params *Params, method string, requestBytes int64, statusCode int, transportErr error) bool {
+	if params.RetryMax <= 0 {
+		return false
+	}
+	if !retryableMethod(method) && requestBytes != 0 {
+		return false
+	}
+	if transportErr != nil {
+		return true
+	}
+	return retryableStatus(params.RetryStatusCodes, statusCode)
+}
+
+// fullJitterBackoff implements the "full jitter" backoff from
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/:
+// delay = rand(0, min(maxDelay, base*2^attempt)).
+func fullJitterBackoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	upper := base << uint(attempt)
+	if upper <= 0 || (maxDelay > 0 && upper > maxDelay) {
+		upper = maxDelay
+	}
+	if upper <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(upper)))
+}
+
+// retryAfterDelay extracts a server-provided minimum retry delay from
+// Retry-After (seconds or HTTP-date) or the Azure-specific
+// x-ms-retry-after-ms, preferring the latter when both are present.
+func retryAfterDelay(header http.Header) (time.Duration, bool) {
+	if ms := header.Get("x-ms-retry-after-ms"); ms != "" {
+		if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			return time.Duration(n) * time.Millisecond, true
+		}
+	}
+	if ra := header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.ParseInt(ra, 10, 64); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if when, err := http.ParseTime(ra); err == nil {
+			if d := time.Until(when); d > 0 {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// nextDelay computes how long to sleep before the next attempt, using
+// the server's Retry-After as a lower bound on the jittered backoff when
+// one is present.
+func nextDelay(params *Params, attempt int, header http.Header) time.Duration {
+	delay := fullJitterBackoff(params.RetryBaseDelay, params.RetryMaxDelay, attempt)
+	if header != nil {
+		if min, ok := retryAfterDelay(header); ok && min > delay {
+			delay = min
+		}
+	}
+	return delay
+}
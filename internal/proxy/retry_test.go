@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	cases := []struct {
+		name         string
+		params       *Params
+		method       string
+		requestBytes int64
+		statusCode   int
+		transportErr error
+		want         bool
+	}{
+		{
+			name:   "retries disabled",
+			params: &Params{RetryMax: 0, RetryStatusCodes: []int{500}},
+			method: http.MethodGet,
+			want:   false,
+		},
+		{
+			name:         "non-idempotent method with a body is not retried",
+			params:       &Params{RetryMax: 3, RetryStatusCodes: []int{500}},
+			method:       http.MethodPost,
+			requestBytes: 10,
+			statusCode:   500,
+			want:         false,
+		},
+		{
+			name:         "idempotent method with a body retries on a configured status",
+			params:       &Params{RetryMax: 3, RetryStatusCodes: []int{500}},
+			method:       http.MethodPut,
+			requestBytes: 10,
+			statusCode:   500,
+			want:         true,
+		},
+		{
+			name:         "non-idempotent method with no body retries on transport error",
+			params:       &Params{RetryMax: 3, RetryStatusCodes: []int{500}},
+			method:       http.MethodPost,
+			requestBytes: 0,
+			transportErr: http.ErrHandlerTimeout,
+			want:         true,
+		},
+		{
+			name:       "unconfigured status is not retried",
+			params:     &Params{RetryMax: 3, RetryStatusCodes: []int{500}},
+			method:     http.MethodGet,
+			statusCode: 503,
+			want:       false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := shouldRetry(c.params, c.method, c.requestBytes, c.statusCode, c.transportErr)
+			if got != c.want {
+				t.Fatalf("shouldRetry() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	maxDelay := time.Second
+
+	for attempt := 0; attempt < 10; attempt++ {
+		for i := 0; i < 20; i++ {
+			d := fullJitterBackoff(base, maxDelay, attempt)
+			if d < 0 || d > maxDelay {
+				t.Fatalf("attempt %d: fullJitterBackoff() = %v, want within [0, %v]", attempt, d, maxDelay)
+			}
+		}
+	}
+}
+
+func TestFullJitterBackoffZeroMaxDelay(t *testing.T) {
+	if d := fullJitterBackoff(100*time.Millisecond, 0, 0); d < 0 || d > 100*time.Millisecond {
+		t.Fatalf("fullJitterBackoff() = %v, want within [0, 100ms]", d)
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	cases := []struct {
+		name   string
+		header http.Header
+		wantOK bool
+		want   time.Duration
+	}{
+		{
+			name:   "no headers",
+			header: http.Header{},
+			wantOK: false,
+		},
+		{
+			name:   "Retry-After in seconds",
+			header: http.Header{"Retry-After": []string{"5"}},
+			wantOK: true,
+			want:   5 * time.Second,
+		},
+		{
+			name: "x-ms-retry-after-ms takes precedence over Retry-After",
+			header: http.Header{
+				"X-Ms-Retry-After-Ms": []string{"250"},
+				"Retry-After":         []string{"5"},
+			},
+			wantOK: true,
+			want:   250 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := retryAfterDelay(c.header)
+			if ok != c.wantOK {
+				t.Fatalf("retryAfterDelay() ok = %v, want %v", ok, c.wantOK)
+			}
+			if ok && got != c.want {
+				t.Fatalf("retryAfterDelay() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfterDelayHTTPDate(t *testing.T) {
+	when := time.Now().Add(10 * time.Second).UTC()
+	header := http.Header{"Retry-After": []string{when.Format(http.TimeFormat)}}
+
+	got, ok := retryAfterDelay(header)
+	if !ok {
+		t.Fatalf("retryAfterDelay() ok = false, want true")
+	}
+	if got <= 0 || got > 10*time.Second {
+		t.Fatalf("retryAfterDelay() = %v, want within (0, 10s]", got)
+	}
+}
@@ -0,0 +1,160 @@
+// Package telemetry wires the proxy into an OTel TracerProvider and a
+// Prometheus registry: it configures the global tracer used by
+// proxy.Proxy, serves /metrics on a dedicated listener, and implements
+// proxy.Metrics on top of the registered collectors.
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"azure-blockchain-connector/internal/proxy"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Config configures Init.
+type Config struct {
+	ServiceName  string
+	OTelEndpoint string  // OTLP/gRPC collector address, e.g. "localhost:4317"
+	SampleRatio  float64 // fraction of traces to sample, 0.0-1.0
+	MetricsAddr  string  // bind address for the /metrics endpoint; empty disables it
+}
+
+// Init configures the global OTel TracerProvider and, if cfg.MetricsAddr
+// is set, starts a /metrics HTTP server. It returns a shutdown func that
+// flushes the tracer provider and stops the metrics server; callers
+// should defer it (or call it from a signal handler).
+func Init(cfg Config) (shutdown func(context.Context) error, err error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var tp *sdktrace.TracerProvider
+	if cfg.OTelEndpoint != "" {
+		exporter, err := otlptracegrpc.New(context.Background(),
+			otlptracegrpc.WithEndpoint(cfg.OTelEndpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		tp = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		)
+	} else {
+		// No collector configured: keep spans (and their context
+		// propagation) working locally without ever exporting them.
+		tp = sdktrace.NewTracerProvider(sdktrace.WithResource(res))
+	}
+	otel.SetTracerProvider(tp)
+	// The global propagator defaults to a no-op, so without this,
+	// proxy.go's otel.GetTextMapPropagator().Inject(...) would never
+	// write traceparent/tracestate onto the upstream request.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var metricsSrv *http.Server
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsSrv = &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+		go func() {
+			_ = metricsSrv.ListenAndServe()
+		}()
+	}
+
+	return func(ctx context.Context) error {
+		if metricsSrv != nil {
+			_ = metricsSrv.Shutdown(ctx)
+		}
+		return tp.Shutdown(ctx)
+	}, nil
+}
+
+// Recorder implements proxy.Metrics on top of a fixed set of Prometheus
+// collectors registered against the default registry.
+type Recorder struct {
+	requestsTotal      *prometheus.CounterVec
+	requestsInFlight   prometheus.Gauge
+	requestLatency     *prometheus.HistogramVec
+	responseBytes      prometheus.Histogram
+	tokenRefreshTotal  *prometheus.CounterVec
+	tokenRefreshErrors *prometheus.CounterVec
+	authLatency        *prometheus.HistogramVec
+}
+
+// NewRecorder registers the proxy's Prometheus collectors and returns a
+// Recorder ready to be used as a proxy.Metrics.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		requestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "azure_blockchain_connector_requests_total",
+			Help: "Total number of proxied requests, labeled by provider and outcome.",
+		}, []string{"provider", "status"}),
+		requestsInFlight: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "azure_blockchain_connector_requests_in_flight",
+			Help: "Number of proxied requests currently being served.",
+		}),
+		requestLatency: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "azure_blockchain_connector_request_duration_seconds",
+			Help:    "Latency of proxied requests, labeled by provider.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"provider"}),
+		responseBytes: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "azure_blockchain_connector_response_bytes",
+			Help:    "Size of proxied responses in bytes.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}),
+		tokenRefreshTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "azure_blockchain_connector_token_refresh_total",
+			Help: "Total number of token acquisition/refresh attempts, labeled by provider.",
+		}, []string{"provider"}),
+		tokenRefreshErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "azure_blockchain_connector_token_refresh_errors_total",
+			Help: "Total number of failed token acquisition/refresh attempts, labeled by provider.",
+		}, []string{"provider"}),
+		authLatency: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "azure_blockchain_connector_auth_latency_seconds",
+			Help:    "Latency of provider auth events, labeled by provider and event.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"provider", "event"}),
+	}
+}
+
+func (r *Recorder) IncInFlight() { r.requestsInFlight.Inc() }
+func (r *Recorder) DecInFlight() { r.requestsInFlight.Dec() }
+
+func (r *Recorder) ObserveRequest(entry proxy.RequestLogEntry) {
+	status := "ok"
+	if entry.Err != nil {
+		status = "error"
+	}
+	r.requestsTotal.WithLabelValues(entry.Provider, status).Inc()
+	r.requestLatency.WithLabelValues(entry.Provider).Observe(entry.Latency.Seconds())
+	r.responseBytes.Observe(float64(entry.ResponseBytes))
+}
+
+func (r *Recorder) ObserveAuth(entry proxy.AuthLogEntry) {
+	// Cache hits are not acquisition/refresh attempts; they show up only
+	// in authLatency under their own event label.
+	if entry.Event != "token_cache_hit" {
+		r.tokenRefreshTotal.WithLabelValues(entry.Provider).Inc()
+		if entry.Err != nil {
+			r.tokenRefreshErrors.WithLabelValues(entry.Provider).Inc()
+		}
+	}
+	r.authLatency.WithLabelValues(entry.Provider, entry.Event).Observe(entry.Latency.Seconds())
+}
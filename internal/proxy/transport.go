@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	HTTPVersionAuto = "auto"
+	HTTPVersion11   = "1.1"
+	HTTPVersion2    = "2"
+)
+
+// TransportConfig configures the single *http.Transport shared by every
+// Provider, so high-throughput blockchain RPC workloads reuse connections
+// (and, on HTTP/2, streams) instead of paying a TLS handshake per request.
+type TransportConfig struct {
+	// HTTPVersion selects HTTPVersionAuto (negotiate via ALPN, the
+	// default), HTTPVersion11 (force HTTP/1.1) or HTTPVersion2 (force
+	// HTTP/2 over TLS).
+	HTTPVersion string
+
+	MaxIdleConnsPerHost   int
+	IdleConnTimeout       time.Duration
+	ResponseHeaderTimeout time.Duration
+	ExpectContinueTimeout time.Duration
+
+	// ReadIdleTimeout and PingTimeout detect dead TCP connections on
+	// long-lived HTTP/2 sessions: if no frame is read for
+	// ReadIdleTimeout, a PING is sent, and the connection is closed if no
+	// ack arrives within PingTimeout. Both are HTTP/2-only.
+	ReadIdleTimeout time.Duration
+	PingTimeout     time.Duration
+
+	CertPath string
+	Insecure bool
+}
+
+// NewTransport builds the shared transport described by cfg.
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	rootCAs, err := loadRootCAs(cfg.CertPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading root CA from %q: %w", cfg.CertPath, err)
+	}
+
+	t1 := &http.Transport{
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: cfg.ExpectContinueTimeout,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: cfg.Insecure,
+			RootCAs:            rootCAs,
+		},
+	}
+
+	if cfg.HTTPVersion == HTTPVersion11 {
+		// An empty, non-nil TLSNextProto disables protocol upgrades
+		// (including HTTP/2) negotiated via ALPN.
+		t1.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return t1, nil
+	}
+
+	t2, err := http2.ConfigureTransports(t1)
+	if err != nil {
+		if cfg.HTTPVersion == HTTPVersion2 {
+			return nil, fmt.Errorf("configuring HTTP/2 transport: %w", err)
+		}
+		// HTTPVersionAuto: fall back to HTTP/1.1-only if HTTP/2 can't be
+		// configured for this transport.
+		return t1, nil
+	}
+	t2.ReadIdleTimeout = cfg.ReadIdleTimeout
+	t2.PingTimeout = cfg.PingTimeout
+
+	if cfg.HTTPVersion == HTTPVersion2 {
+		// ConfigureTransports only arranges for ALPN to offer h2 as one
+		// option; a remote that doesn't speak it silently leaves the
+		// connection on HTTP/1.1. Only advertise h2 so a non-HTTP/2
+		// remote fails the handshake instead of silently downgrading.
+		t1.TLSClientConfig.NextProtos = []string{"h2"}
+	}
+	return t1, nil
+}
+
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return pool, nil
+}